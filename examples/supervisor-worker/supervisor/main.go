@@ -1,23 +1,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/vladimirvivien/horizon/pkg/api"
 	"github.com/vladimirvivien/horizon/pkg/coordinator"
+	"github.com/vladimirvivien/horizon/pkg/spec"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 func main() {
-	var kubeconfig, ns, image string
+	var kubeconfig, ns, image, specFile string
 	flag.StringVar(&ns, "namespace", "default", "namespace")
 	flag.StringVar(&image, "worker-image", "worker:latest", "container image for worker process")
 	flag.StringVar(&kubeconfig, "kubeconfig", kubeconfig, "kubeconfig file")
+	flag.StringVar(&specFile, "spec", "", "YAML or JSON file describing the workers, replaces the default worker")
 	flag.Parse()
 
 	stopCh := make(chan struct{})
@@ -45,34 +49,14 @@ func main() {
 		log.Fatalf("failed to start greeter-supervisor: %s", err)
 	}
 
-	// describe callback
-	coord.OnDeploymentEvent(func(e api.DeploymentEvent) {
-		switch e.Type {
-		case api.DeploymentEventNew:
-			log.Printf("Deployment \"%s\" received\n", e.Name)
-		case api.DeploymentEventUpdate:
-			if e.Ready {
-				log.Printf("Deployment \"%s\" ready!\n", e.Name)
-			}
-		}
-	})
-
 	coord.OnPodEvent(func(e api.PodEvent) {
-		log.Println("Rcvd pod event")
 		if e.Running {
-			addr := e.PodIP
-			res, err := http.Get(fmt.Sprintf("http://%s:%d/", addr, e.Port))
-			if err != nil {
-				log.Println("unable to connect to worker process:", err)
-				return
-			}
-			msg, err := ioutil.ReadAll(res.Body)
-			defer res.Body.Close()
-			if err != nil {
-				log.Println("failed to read message from worker:", err)
-				return
+			log.Printf("Pod %s of %s %s running at %s on %s\n", e.Name, e.OwnerKind, e.OwnerName, e.PodIP, e.NodeName)
+		}
+		for _, status := range e.ContainerStatuses {
+			if status.State == "Waiting" && status.RestartCount > 0 {
+				log.Printf("Pod %s container %s restarted %d times: %s\n", e.Name, status.Name, status.RestartCount, status.Reason)
 			}
-			log.Println(msg)
 		}
 	})
 
@@ -81,19 +65,69 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// apply an operation
-	if err := coord.Run(api.RunParam{
+	params := []api.RunParam{{
 		Replicas:        1,
 		Name:            "worker",
 		Namespace:       ns,
 		Image:           image,
 		Port:            8086,
 		ImagePullPolicy: "Never",
-	}); err != nil {
-		log.Fatal(err)
+		Service:         api.ServiceClusterIP,
+	}}
+	if specFile != "" {
+		params, err = spec.LoadFile(specFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// apply an operation
+	for _, param := range params {
+		workload, err := coord.RunWorkload(param)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go waitAndGreet(workload, param.Port)
 	}
 
 	select {
 	case <-stopCh:
 	}
 }
+
+// waitAndGreet greets the workers of workload once it is ready
+func waitAndGreet(workload api.Workload, port int64) {
+	defer workload.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := workload.WaitReady(ctx); err != nil {
+		log.Println(err)
+		return
+	}
+	log.Printf("%s \"%s\" ready!\n", workload.Kind(), workload.Name())
+
+	status, err := workload.Status()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if status.ServiceDNS != "" && port != 0 {
+		greet(status.ServiceDNS, port)
+	}
+}
+
+// greet calls the workers through their service
+func greet(host string, port int64) {
+	res, err := http.Get(fmt.Sprintf("http://%s:%d/", host, port))
+	if err != nil {
+		log.Println("unable to connect to worker process:", err)
+		return
+	}
+	defer res.Body.Close()
+	msg, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Println("failed to read message from worker:", err)
+		return
+	}
+	log.Println(string(msg))
+}
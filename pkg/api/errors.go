@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ConflictError is returned when an object with the requested name already
+// exists but is not managed by the coordinator attempting to change it.
+type ConflictError struct {
+	Kind        string
+	Namespace   string
+	Name        string
+	Coordinator string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %s/%s exists and is not managed by coordinator %s", e.Kind, e.Namespace, e.Name, e.Coordinator)
+}
+
+// IsConflictError returns true if err is a *ConflictError
+func IsConflictError(err error) bool {
+	_, ok := err.(*ConflictError)
+	return ok
+}
+
+// ValidationError is returned when a RunParam is rejected before
+// reaching the API server. Errors lists every invalid field.
+type ValidationError struct {
+	Errors field.ErrorList
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid run param: %s", e.Errors.ToAggregate())
+}
+
+// IsValidationError returns true if err is a *ValidationError
+func IsValidationError(err error) bool {
+	_, ok := err.(*ValidationError)
+	return ok
+}
+
+// JobChangedError is returned when a Job is run again with a spec
+// that differs from the one it was created with. The spec of a Job
+// cannot be updated, the Job must be deleted first.
+type JobChangedError struct {
+	Namespace string
+	Name      string
+}
+
+func (e *JobChangedError) Error() string {
+	return fmt.Sprintf("job %s/%s exists with a different spec, delete it first", e.Namespace, e.Name)
+}
+
+// IsJobChangedError returns true if err is a *JobChangedError
+func IsJobChangedError(err error) bool {
+	_, ok := err.(*JobChangedError)
+	return ok
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Manifest holds the objects generated for a RunParam:
+// the workload first, followed by its companion objects
+type Manifest struct {
+	Objects []*unstructured.Unstructured
+}
+
+// JSON returns the objects as an indented v1 List
+func (m *Manifest) JSON() ([]byte, error) {
+	items := make([]interface{}, 0, len(m.Objects))
+	for _, obj := range m.Objects {
+		items = append(items, obj.Object)
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+	}, "", "  ")
+}
+
+// YAML returns the objects as a stream of YAML documents
+func (m *Manifest) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	for i, obj := range m.Objects {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		doc, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(doc)
+	}
+	return buf.Bytes(), nil
+}
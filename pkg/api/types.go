@@ -1,24 +1,368 @@
 package api
 
 import (
+	"time"
+
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var (
-	DeploymentsResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
-	PodsResource        = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+	DeploymentsResource     = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	StatefulSetsResource    = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+	DaemonSetsResource      = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}
+	JobsResource            = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+	CronJobsResource        = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}
+	ReplicaSetsResource     = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+	PodsResource            = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+	ServicesResource        = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "services"}
+	ConfigMapsResource      = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+	SecretsResource         = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "secrets"}
+	ServiceAccountsResource = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "serviceaccounts"}
+	RolesResource           = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"}
+	RoleBindingsResource    = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"}
+)
+
+type WorkloadKind string
+
+const (
+	WorkloadDeployment  WorkloadKind = "Deployment"
+	WorkloadStatefulSet WorkloadKind = "StatefulSet"
+	WorkloadJob         WorkloadKind = "Job"
+	WorkloadCronJob     WorkloadKind = "CronJob"
+	WorkloadDaemonSet   WorkloadKind = "DaemonSet"
 )
 
 type RunParam struct {
-	Namespace       string
-	Name            string
-	Image           string
-	ImagePullPolicy string
-	Port            int64
-	Envs            []string
-	Labels          string
-	Replicas        int64
+	// Kind of workload to run, defaults to WorkloadDeployment
+	Kind WorkloadKind `json:"kind,omitempty"`
+	// Namespace defaults to the namespace of the coordinator client
+	Namespace       string `json:"namespace,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Image           string `json:"image,omitempty"`
+	ImagePullPolicy string `json:"imagePullPolicy,omitempty"`
+	// Port is a shorthand for a TCP port named "api"
+	Port int64 `json:"port,omitempty"`
+	// Ports are exposed by the worker container, and by its
+	// Service if any, along with Port. Names are required.
+	Ports    []ContainerPort `json:"ports,omitempty"`
+	Envs     []string        `json:"envs,omitempty"`
+	Labels   string          `json:"labels,omitempty"`
+	Replicas int64           `json:"replicas,omitempty"`
+	// Command and Args override the image entrypoint and cmd.
+	// Env references such as $(POD_IP) are expanded by the kubelet.
+	Command        []string  `json:"command,omitempty"`
+	Args           []string  `json:"args,omitempty"`
+	WorkingDir     string    `json:"workingDir,omitempty"`
+	Requests       Resources `json:"requests,omitempty"`
+	Limits         Resources `json:"limits,omitempty"`
+	LivenessProbe  *Probe    `json:"livenessProbe,omitempty"`
+	ReadinessProbe *Probe    `json:"readinessProbe,omitempty"`
+	StartupProbe   *Probe    `json:"startupProbe,omitempty"`
+	// HTTPReadiness adds an HTTP GET readiness probe against
+	// Port when ReadinessProbe is not set
+	HTTPReadiness bool     `json:"httpReadiness,omitempty"`
+	Volumes       []Volume `json:"volumes,omitempty"`
+	// InitContainers run to completion, in order, before the
+	// worker container and Sidecars are started
+	InitContainers []Container `json:"initContainers,omitempty"`
+	Sidecars       []Container `json:"sidecars,omitempty"`
+	// Service, if set, exposes Port through a Service
+	// named after the workload. StatefulSets always get
+	// a headless governing Service.
+	Service ServiceType `json:"service,omitempty"`
+	// VolumeClaims are provisioned per StatefulSet pod
+	VolumeClaims []VolumeClaim `json:"volumeClaims,omitempty"`
+	// PodManagement is the StatefulSet pod management
+	// policy, OrderedReady (default) or Parallel
+	PodManagement string `json:"podManagement,omitempty"`
+	// Completions, Parallelism, BackoffLimit and ActiveDeadlineSeconds
+	// configure Job runs, including those spawned by a CronJob.
+	// A nil BackoffLimit uses the cluster default.
+	Completions           int64  `json:"completions,omitempty"`
+	Parallelism           int64  `json:"parallelism,omitempty"`
+	BackoffLimit          *int64 `json:"backoffLimit,omitempty"`
+	ActiveDeadlineSeconds int64  `json:"activeDeadlineSeconds,omitempty"`
+	// Schedule, in cron format, is required by CronJobs
+	Schedule string `json:"schedule,omitempty"`
+	// ConcurrencyPolicy is the CronJob concurrency policy,
+	// Allow (default), Forbid or Replace
+	ConcurrencyPolicy string `json:"concurrencyPolicy,omitempty"`
+	// SuccessfulJobsHistoryLimit and FailedJobsHistoryLimit bound
+	// the finished Jobs kept by a CronJob. Nil uses the cluster default.
+	SuccessfulJobsHistoryLimit *int64 `json:"successfulJobsHistoryLimit,omitempty"`
+	FailedJobsHistoryLimit     *int64 `json:"failedJobsHistoryLimit,omitempty"`
+	// Suspend creates the CronJob without scheduling runs
+	Suspend bool `json:"suspend,omitempty"`
+	// NodeSelector restricts the worker pods to matching nodes
+	NodeSelector    map[string]string          `json:"nodeSelector,omitempty"`
+	NodeAffinity    *NodeAffinity              `json:"nodeAffinity,omitempty"`
+	PodAffinity     []PodAffinityTerm          `json:"podAffinity,omitempty"`
+	PodAntiAffinity []PodAffinityTerm          `json:"podAntiAffinity,omitempty"`
+	Tolerations     []Toleration               `json:"tolerations,omitempty"`
+	TopologySpread  []TopologySpreadConstraint `json:"topologySpread,omitempty"`
+	// SpreadAcrossNodes prefers scheduling the worker pods
+	// on distinct nodes, using pod anti-affinity against
+	// the labels of the workload pods
+	SpreadAcrossNodes bool `json:"spreadAcrossNodes,omitempty"`
+	// UpdateStrategy is the DaemonSet update strategy,
+	// RollingUpdate (default) or OnDelete
+	UpdateStrategy string `json:"updateStrategy,omitempty"`
+	// MaxUnavailable bounds the DaemonSet pods unavailable
+	// during a rolling update, as a number or a percentage
+	MaxUnavailable string `json:"maxUnavailable,omitempty"`
+	// Detached leaves the created objects without an owner
+	// reference to the supervisor, so that they outlive it
+	Detached bool `json:"detached,omitempty"`
+	// ServiceAccount runs the worker pods as a ServiceAccount
+	// named after the workload instead of the namespace default
+	ServiceAccount bool `json:"serviceAccount,omitempty"`
+	// Permissions are granted to the worker ServiceAccount
+	// through a Role and RoleBinding named after the workload,
+	// and imply ServiceAccount
+	Permissions []Permission `json:"permissions,omitempty"`
+	// SecurityContext applies to the worker pods and to
+	// every container in them
+	SecurityContext *SecurityContext `json:"securityContext,omitempty"`
+}
+
+// SecurityPresetRestricted defaults the security context to
+// a spec passing the restricted Pod Security Standard
+const SecurityPresetRestricted = "restricted"
+
+// SecurityContext sets the identity and privileges of the worker
+// pods. RunAsUser, RunAsGroup, RunAsNonRoot, FSGroup and
+// SeccompProfile apply to the pod, the other fields to each of its
+// containers. Preset defaults the fields left unset.
+type SecurityContext struct {
+	Preset                   string   `json:"preset,omitempty"`
+	RunAsUser                *int64   `json:"runAsUser,omitempty"`
+	RunAsGroup               *int64   `json:"runAsGroup,omitempty"`
+	RunAsNonRoot             *bool    `json:"runAsNonRoot,omitempty"`
+	FSGroup                  *int64   `json:"fsGroup,omitempty"`
+	ReadOnlyRootFilesystem   bool     `json:"readOnlyRootFilesystem,omitempty"`
+	AllowPrivilegeEscalation *bool    `json:"allowPrivilegeEscalation,omitempty"`
+	DropCapabilities         []string `json:"dropCapabilities,omitempty"`
+	AddCapabilities          []string `json:"addCapabilities,omitempty"`
+	// SeccompProfile is RuntimeDefault, Unconfined or
+	// Localhost/<profile>, relative to the kubelet seccomp root
+	SeccompProfile string `json:"seccompProfile,omitempty"`
+}
+
+// Permission is a rule of the Role granted to the worker
+// ServiceAccount. APIGroups defaults to the core group.
+// RBAC rules cannot select objects by label, ResourceNames
+// narrows a rule down to the named objects.
+type Permission struct {
+	APIGroups     []string `json:"apiGroups,omitempty"`
+	Resources     []string `json:"resources,omitempty"`
+	ResourceNames []string `json:"resourceNames,omitempty"`
+	Verbs         []string `json:"verbs,omitempty"`
+}
+
+// VolumeClaim is a StatefulSet volume claim template
+// mounted into the worker container at MountPath.
+// AccessModes defaults to ReadWriteOnce.
+type VolumeClaim struct {
+	Name         string   `json:"name,omitempty"`
+	MountPath    string   `json:"mountPath,omitempty"`
+	Size         string   `json:"size,omitempty"`
+	StorageClass string   `json:"storageClass,omitempty"`
+	AccessModes  []string `json:"accessModes,omitempty"`
+}
+
+type ServiceType string
+
+const (
+	ServiceNone      ServiceType = ""
+	ServiceClusterIP ServiceType = "ClusterIP"
+	ServiceHeadless  ServiceType = "Headless"
+)
+
+// NodeAffinity constrains the nodes the worker pods run on.
+// All Required expressions must match, Preferred terms add
+// their weight to the nodes they match.
+type NodeAffinity struct {
+	Required  []NodeSelectorRequirement `json:"required,omitempty"`
+	Preferred []PreferredNodeSelector   `json:"preferred,omitempty"`
+}
+
+// NodeSelectorRequirement matches node labels. Operator is one
+// of In, NotIn, Exists, DoesNotExist, Gt or Lt.
+type NodeSelectorRequirement struct {
+	Key      string   `json:"key,omitempty"`
+	Operator string   `json:"operator,omitempty"`
+	Values   []string `json:"values,omitempty"`
+}
+
+// PreferredNodeSelector is a node affinity preference,
+// Weight ranges from 1 to 100.
+type PreferredNodeSelector struct {
+	Weight       int64                     `json:"weight,omitempty"`
+	Requirements []NodeSelectorRequirement `json:"requirements,omitempty"`
+}
+
+// PodAffinityTerm selects the pods matching Labels in Namespaces
+// (default the workload namespace) and co-locates with, or
+// avoids, them within TopologyKey. A Weight from 1 to 100 makes
+// the term a preference, a zero Weight makes it required.
+type PodAffinityTerm struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Namespaces  []string          `json:"namespaces,omitempty"`
+	TopologyKey string            `json:"topologyKey,omitempty"`
+	Weight      int64             `json:"weight,omitempty"`
+}
+
+// Toleration lets the worker pods schedule onto tainted nodes.
+// Operator is Equal (default) or Exists.
+type Toleration struct {
+	Key               string `json:"key,omitempty"`
+	Operator          string `json:"operator,omitempty"`
+	Value             string `json:"value,omitempty"`
+	Effect            string `json:"effect,omitempty"`
+	TolerationSeconds *int64 `json:"tolerationSeconds,omitempty"`
+}
+
+// TopologySpreadConstraint bounds the skew of the worker pods
+// across TopologyKey domains. WhenUnsatisfiable is DoNotSchedule
+// (default) or ScheduleAnyway. MaxSkew defaults to 1.
+type TopologySpreadConstraint struct {
+	MaxSkew           int64  `json:"maxSkew,omitempty"`
+	TopologyKey       string `json:"topologyKey,omitempty"`
+	WhenUnsatisfiable string `json:"whenUnsatisfiable,omitempty"`
+}
+
+// Container describes an init or sidecar container that
+// runs in the worker pod next to the worker container
+type Container struct {
+	Name            string          `json:"name,omitempty"`
+	Image           string          `json:"image,omitempty"`
+	ImagePullPolicy string          `json:"imagePullPolicy,omitempty"`
+	Command         []string        `json:"command,omitempty"`
+	Args            []string        `json:"args,omitempty"`
+	WorkingDir      string          `json:"workingDir,omitempty"`
+	Envs            []string        `json:"envs,omitempty"`
+	Ports           []ContainerPort `json:"ports,omitempty"`
+}
+
+// ContainerPort is a port exposed by a container.
+// Protocol defaults to TCP.
+type ContainerPort struct {
+	Name     string `json:"name,omitempty"`
+	Port     int64  `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// Volume is mounted into the worker container at MountPath.
+// Exactly one of ConfigMap, Secret, PersistentVolumeClaim
+// or EmptyDir must be set.
+type Volume struct {
+	Name      string `json:"name,omitempty"`
+	MountPath string `json:"mountPath,omitempty"`
+	SubPath   string `json:"subPath,omitempty"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+
+	// ConfigMap, Secret and PersistentVolumeClaim name
+	// existing objects in the workload namespace
+	ConfigMap             string    `json:"configMap,omitempty"`
+	Secret                string    `json:"secret,omitempty"`
+	PersistentVolumeClaim string    `json:"persistentVolumeClaim,omitempty"`
+	EmptyDir              *EmptyDir `json:"emptyDir,omitempty"`
+}
+
+// EmptyDir is scratch space that lives as long as the pod.
+// Medium may be "" (node storage) or "Memory".
+type EmptyDir struct {
+	Medium    string `json:"medium,omitempty"`
+	SizeLimit string `json:"sizeLimit,omitempty"`
+}
+
+// Probe describes a container health check. Exactly one of
+// HTTP, TCP or Exec must be set.
+type Probe struct {
+	HTTP                *HTTPProbe `json:"http,omitempty"`
+	TCP                 *TCPProbe  `json:"tcp,omitempty"`
+	Exec                []string   `json:"exec,omitempty"`
+	InitialDelaySeconds int64      `json:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int64      `json:"periodSeconds,omitempty"`
+	TimeoutSeconds      int64      `json:"timeoutSeconds,omitempty"`
+	SuccessThreshold    int64      `json:"successThreshold,omitempty"`
+	FailureThreshold    int64      `json:"failureThreshold,omitempty"`
+}
+
+// HTTPProbe checks for a successful response from an HTTP GET.
+// Port defaults to RunParam.Port.
+type HTTPProbe struct {
+	Path   string `json:"path,omitempty"`
+	Port   int64  `json:"port,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// TCPProbe checks that a TCP connection can be opened.
+// Port defaults to RunParam.Port.
+type TCPProbe struct {
+	Port int64 `json:"port,omitempty"`
+}
+
+// Resources holds container compute resources expressed
+// as Kubernetes quantities such as "250m" or "64Mi"
+type Resources struct {
+	CPU              string `json:"cpu,omitempty"`
+	Memory           string `json:"memory,omitempty"`
+	EphemeralStorage string `json:"ephemeralStorage,omitempty"`
+}
+
+type DeletePolicy string
+
+const (
+	DeleteBackground DeletePolicy = "Background"
+	DeleteForeground DeletePolicy = "Foreground"
+	DeleteOrphan     DeletePolicy = "Orphan"
+)
+
+type DeleteParam struct {
+	// Kind of workload to delete, defaults to WorkloadDeployment
+	Kind      WorkloadKind
+	Namespace string
+	Name      string
+	Policy    DeletePolicy
+	// Wait blocks until all pods of the workload are gone
+	Wait    bool
+	Timeout time.Duration
+}
+
+type ScaleParam struct {
+	Namespace string
+	Name      string
+	Replicas  int64
+	// Timeout bounds the wait for the ready replicas to reach Replicas
+	Timeout time.Duration
+}
+
+// SuspendParam suspends or resumes the schedule of a CronJob.
+// Jobs already running are not affected.
+type SuspendParam struct {
+	Namespace string
+	Name      string
+	Suspend   bool
+}
+
+type UpdateParam struct {
+	Namespace string
+	Name      string
+	// Container to update, defaults to Name
+	Container string
+	Image     string
+	// ProgressDeadlineSeconds, if set, replaces the deployment progress deadline
+	ProgressDeadlineSeconds int64
+	// Wait blocks until the rollout completes or fails. Timeout
+	// defaults to the progress deadline of the deployment plus
+	// a margin, and must exceed it for RollbackOnFailure.
+	Wait    bool
+	Timeout time.Duration
+	// RollbackOnFailure reverts to the previous revision when
+	// the rollout exceeds its progress deadline (requires Wait)
+	RollbackOnFailure bool
 }
 
 type EventFunc func()
@@ -44,18 +388,157 @@ const (
 	DeploymentEventNew
 	DeploymentEventUpdate
 	DeploymentEventDelete
+	DeploymentEventRolloutProgressing
+	DeploymentEventRolloutComplete
+	DeploymentEventRolloutFailed
 )
 
+// Condition reports a status condition of a workload or pod
+type Condition struct {
+	Type    string
+	Status  string
+	Reason  string
+	Message string
+}
+
+// DeploymentCondition reports a status condition of a Deployment
+type DeploymentCondition = Condition
+
 type DeploymentEvent struct {
-	Type          DeploymentEventType
-	Name          string
-	Namespace     string
-	Port          int64
-	ReadyReplicas int64
-	Ready         bool
-	Source        *unstructured.Unstructured
+	Type               DeploymentEventType
+	Name               string
+	Namespace          string
+	Port               int64
+	Replicas           int64
+	ReadyReplicas      int64
+	UpdatedReplicas    int64
+	AvailableReplicas  int64
+	Generation         int64
+	ObservedGeneration int64
+	Revision           string
+	Conditions         []DeploymentCondition
+	// ServiceDNS is the DNS name of the companion service, if any
+	ServiceDNS string
+	Ready      bool
+	Source     *unstructured.Unstructured
 }
 
+type StatefulSetEventType int
+
+const (
+	StatefulSetEventUnknown StatefulSetEventType = iota
+	StatefulSetEventNew
+	StatefulSetEventUpdate
+	StatefulSetEventDelete
+	StatefulSetEventOrdinalReady
+	StatefulSetEventOrdinalNotReady
+)
+
+// OrdinalStatus is the readiness of the pod holding an ordinal
+type OrdinalStatus struct {
+	Ordinal int64
+	PodName string
+	Ready   bool
+}
+
+type StatefulSetEvent struct {
+	Type            StatefulSetEventType
+	Name            string
+	Namespace       string
+	Replicas        int64
+	ReadyReplicas   int64
+	CurrentReplicas int64
+	UpdatedReplicas int64
+	Ready           bool
+	// Ordinal is the ordinal whose readiness changed,
+	// set for the ordinal event types only
+	Ordinal OrdinalStatus
+	// Ordinals lists the known ordinals in order
+	Ordinals []OrdinalStatus
+	Source   *unstructured.Unstructured
+}
+
+type StatefulSetEventFunc func(StatefulSetEvent)
+
+type DaemonSetEventType int
+
+const (
+	DaemonSetEventUnknown DaemonSetEventType = iota
+	DaemonSetEventNew
+	DaemonSetEventUpdate
+	DaemonSetEventDelete
+)
+
+type DaemonSetEvent struct {
+	Type      DaemonSetEventType
+	Name      string
+	Namespace string
+	// DesiredNumberScheduled is the number of nodes that should run a worker
+	DesiredNumberScheduled int64
+	CurrentNumberScheduled int64
+	UpdatedNumberScheduled int64
+	NumberReady            int64
+	NumberAvailable        int64
+	Ready                  bool
+	Source                 *unstructured.Unstructured
+}
+
+type DaemonSetEventFunc func(DaemonSetEvent)
+
+type JobEventType int
+
+const (
+	JobEventUnknown JobEventType = iota
+	JobEventNew
+	JobEventUpdate
+	JobEventDelete
+	JobEventStarted
+	JobEventSucceeded
+	JobEventFailed
+)
+
+type JobEvent struct {
+	Type      JobEventType
+	Name      string
+	Namespace string
+	Active    int64
+	Succeeded int64
+	Failed    int64
+	// Reason and Message explain a failed job
+	Reason  string
+	Message string
+	Source  *unstructured.Unstructured
+}
+
+type JobEventFunc func(JobEvent)
+
+type CronJobEventType int
+
+const (
+	CronJobEventUnknown CronJobEventType = iota
+	CronJobEventNew
+	CronJobEventUpdate
+	CronJobEventDelete
+	CronJobEventJobScheduled
+)
+
+type CronJobEvent struct {
+	Type      CronJobEventType
+	Name      string
+	Namespace string
+	Schedule  string
+	Suspend   bool
+	// Active is the number of running jobs
+	Active           int64
+	LastScheduleTime time.Time
+	// Job is the name of the spawned job,
+	// set for CronJobEventJobScheduled only
+	Job    string
+	Source *unstructured.Unstructured
+}
+
+type CronJobEventFunc func(CronJobEvent)
+
 type PodEventType int
 
 const (
@@ -73,6 +556,43 @@ type PodEvent struct {
 	HostIP    string
 	PodIP     string
 	Running   bool
+	Phase     string
+	// Ready reports the Ready condition of the pod
+	Ready             bool
+	Conditions        []Condition
+	ContainerStatuses []ContainerStatus
+	// Ports declared by the containers of the pod
+	Ports    []ContainerPort
+	NodeName string
+	Labels   map[string]string
+	// OwnerKind and OwnerName identify the controller of the pod.
+	// Pods of a Deployment report the Deployment rather than
+	// its ReplicaSet.
+	OwnerKind string
+	OwnerName string
+	Source    *unstructured.Unstructured
+}
+
+// ContainerStatus reports a container of a pod. State is Waiting,
+// Running or Terminated, and Reason explains the Waiting and
+// Terminated states.
+type ContainerStatus struct {
+	Name         string
+	Init         bool
+	Ready        bool
+	RestartCount int64
+	State        string
+	Reason       string
+}
+
+// Port returns the pod port with the given name
+func (e PodEvent) Port(name string) (ContainerPort, bool) {
+	for _, port := range e.Ports {
+		if port.Name == name {
+			return port, true
+		}
+	}
+	return ContainerPort{}, false
 }
 
 type PodEventFunc func(PodEvent)
@@ -82,9 +602,20 @@ type DeploymentEventFunc func(DeploymentEvent)
 type Coordinator interface {
 	Start(<-chan struct{}) error
 	Run(RunParam) error
+	RunWorkload(RunParam) (Workload, error)
+	DryRun(RunParam) (*Manifest, error)
+	Render(RunParam) (*Manifest, error)
+	Delete(DeleteParam) error
+	Scale(ScaleParam) error
+	Update(UpdateParam) error
+	Suspend(SuspendParam) error
 	OnCoordEvent(CoordEventFunc) Coordinator
 	OnPodEvent(PodEventFunc) Coordinator
 	OnDeploymentEvent(DeploymentEventFunc) Coordinator
+	OnStatefulSetEvent(StatefulSetEventFunc) Coordinator
+	OnDaemonSetEvent(DaemonSetEventFunc) Coordinator
+	OnJobEvent(JobEventFunc) Coordinator
+	OnCronJobEvent(CronJobEventFunc) Coordinator
 }
 
 type WorkerEventType int
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Workload is a handle on a workload started with
+// Coordinator.RunWorkload. Its queries are answered from
+// the informer caches of the started coordinator.
+type Workload interface {
+	Kind() WorkloadKind
+	Namespace() string
+	Name() string
+	// WaitReady blocks until the workload is ready, or
+	// fails when ctx is done or a Job fails
+	WaitReady(context.Context) error
+	// Status returns the current replica counts
+	Status() (WorkloadStatus, error)
+	// Pods returns the current pods of the workload
+	Pods() ([]WorkloadPod, error)
+	// Events returns the events of the workload and of its pods.
+	// Only the latest event of each object is kept while the
+	// events are not read as fast as they occur.
+	Events() <-chan WorkloadEvent
+	// Close stops the events and closes their channel
+	Close()
+}
+
+// WorkloadStatus reports the replicas of a workload. For Jobs,
+// DesiredReplicas counts the completions and ReadyReplicas the
+// succeeded pods. CronJobs report their active Jobs as ready
+// and are ready as soon as they exist.
+type WorkloadStatus struct {
+	DesiredReplicas int64
+	ReadyReplicas   int64
+	Ready           bool
+	// ServiceDNS is the DNS name of the workload
+	// Service, if one was requested
+	ServiceDNS string
+}
+
+// WorkloadPod is a pod of a workload
+type WorkloadPod struct {
+	Name      string
+	Namespace string
+	HostIP    string
+	PodIP     string
+	Running   bool
+	Ready     bool
+	Ports     []ContainerPort
+}
+
+type WorkloadEventType int
+
+const (
+	WorkloadEventUnknown WorkloadEventType = iota
+	WorkloadEventNew
+	WorkloadEventUpdate
+	WorkloadEventDelete
+	WorkloadEventPodNew
+	WorkloadEventPodUpdate
+	WorkloadEventPodDelete
+)
+
+// WorkloadEvent reports a change of a workload, or of one
+// of its pods for the WorkloadEventPod* types, in which
+// case Pod is set and Status is left empty
+type WorkloadEvent struct {
+	Type      WorkloadEventType
+	Kind      WorkloadKind
+	Name      string
+	Namespace string
+	Status    WorkloadStatus
+	Pod       *WorkloadPod
+	Source    *unstructured.Unstructured
+}
@@ -0,0 +1,173 @@
+package coordinator
+
+import (
+	"fmt"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validateContainers validates the worker ports and the init and sidecar
+// containers of param. Container names must be unique within the pod, and
+// the worker container and sidecars, which share the pod network, must not
+// expose the same port.
+func validateContainers(param api.RunParam) field.ErrorList {
+	var errs field.ErrorList
+	names := map[string]bool{param.Name: true}
+	appPorts := make(map[string]string)
+	if param.Port != 0 {
+		appPorts[portKey(param.Port, "TCP")] = param.Name
+	}
+	portsPath := field.NewPath("ports")
+	for i, port := range param.Ports {
+		if port.Name == "" {
+			errs = append(errs, field.Required(portsPath.Index(i).Child("name"), "worker ports must be named"))
+		} else if param.Port != 0 && port.Name == apiPortName {
+			errs = append(errs, field.Duplicate(portsPath.Index(i).Child("name"), port.Name))
+		}
+	}
+	errs = append(errs, validateContainerPorts(portsPath, param.Name, param.Ports, appPorts)...)
+
+	for i, container := range param.InitContainers {
+		errs = append(errs, validateContainer(field.NewPath("initContainers").Index(i), container, names, make(map[string]string))...)
+	}
+	for i, container := range param.Sidecars {
+		errs = append(errs, validateContainer(field.NewPath("sidecars").Index(i), container, names, appPorts)...)
+	}
+	return errs
+}
+
+// validateContainer validates container and records its name
+// and ports in names and ports to detect duplicates.
+func validateContainer(fldPath *field.Path, container api.Container, names map[string]bool, ports map[string]string) field.ErrorList {
+	var errs field.ErrorList
+	if container.Name == "" {
+		errs = append(errs, field.Required(fldPath.Child("name"), ""))
+	} else if names[container.Name] {
+		errs = append(errs, field.Duplicate(fldPath.Child("name"), container.Name))
+	} else {
+		errs = append(errs, validateDNSLabel(fldPath.Child("name"), container.Name)...)
+	}
+	names[container.Name] = true
+
+	errs = append(errs, validateImage(fldPath.Child("image"), container.Image)...)
+	errs = append(errs, validatePullPolicy(fldPath.Child("imagePullPolicy"), container.ImagePullPolicy)...)
+	errs = append(errs, validateWorkingDir(fldPath.Child("workingDir"), container.WorkingDir)...)
+	errs = append(errs, validateEnvs(fldPath.Child("envs"), container.Envs)...)
+
+	errs = append(errs, validateContainerPorts(fldPath.Child("ports"), container.Name, container.Ports, ports)...)
+	return errs
+}
+
+// validateContainerPorts validates the ports of container and records
+// them in used to detect ports exposed twice within the pod network.
+func validateContainerPorts(fldPath *field.Path, container string, ports []api.ContainerPort, used map[string]string) field.ErrorList {
+	var errs field.ErrorList
+	portNames := make(map[string]bool)
+	for i, port := range ports {
+		portPath := fldPath.Index(i)
+		if port.Port == 0 {
+			errs = append(errs, field.Required(portPath.Child("port"), ""))
+			continue
+		}
+		if portErrs := validatePort(portPath.Child("port"), port.Port); len(portErrs) > 0 {
+			errs = append(errs, portErrs...)
+			continue
+		}
+		protocol := protocolOrDefault(port.Protocol)
+		switch protocol {
+		case "TCP", "UDP", "SCTP":
+		default:
+			errs = append(errs, field.NotSupported(portPath.Child("protocol"), port.Protocol, []string{"TCP", "UDP", "SCTP"}))
+			continue
+		}
+		if port.Name != "" {
+			for _, msg := range validation.IsValidPortName(port.Name) {
+				errs = append(errs, field.Invalid(portPath.Child("name"), port.Name, msg))
+			}
+			if portNames[port.Name] {
+				errs = append(errs, field.Duplicate(portPath.Child("name"), port.Name))
+			}
+			portNames[port.Name] = true
+		}
+		key := portKey(port.Port, protocol)
+		if owner, ok := used[key]; ok {
+			errs = append(errs, field.Invalid(portPath.Child("port"), port.Port, fmt.Sprintf("%s already used by container %s", key, owner)))
+			continue
+		}
+		used[key] = container
+	}
+	return errs
+}
+
+func generateContainer(container api.Container) map[string]interface{} {
+	result := map[string]interface{}{
+		"name":            container.Name,
+		"image":           container.Image,
+		"imagePullPolicy": pullPolicyOrDefault(container.ImagePullPolicy),
+	}
+	if len(container.Command) > 0 {
+		result["command"] = toInterfaceSlice(container.Command)
+	}
+	if len(container.Args) > 0 {
+		result["args"] = toInterfaceSlice(container.Args)
+	}
+	if container.WorkingDir != "" {
+		result["workingDir"] = container.WorkingDir
+	}
+	// container validated by validateContainer
+	if envs, _ := parseEnvs(container.Envs); len(envs) > 0 {
+		result["env"] = envs
+	}
+	if len(container.Ports) > 0 {
+		result["ports"] = generatePorts(container.Ports)
+	}
+	return result
+}
+
+// apiPortName names the worker port set by RunParam.Port
+const apiPortName = "api"
+
+// workerPorts returns the ports of the worker container, the
+// shorthand Port first
+func workerPorts(param api.RunParam) []api.ContainerPort {
+	var ports []api.ContainerPort
+	if param.Port != 0 {
+		ports = append(ports, api.ContainerPort{Name: apiPortName, Port: param.Port, Protocol: "TCP"})
+	}
+	return append(ports, param.Ports...)
+}
+
+func generatePorts(ports []api.ContainerPort) []interface{} {
+	var result []interface{}
+	for _, port := range ports {
+		p := map[string]interface{}{
+			"containerPort": port.Port,
+			"protocol":      protocolOrDefault(port.Protocol),
+		}
+		if port.Name != "" {
+			p["name"] = port.Name
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+func pullPolicyOrDefault(policy string) string {
+	if policy == "" {
+		return "IfNotPresent"
+	}
+	return policy
+}
+
+func protocolOrDefault(protocol string) string {
+	if protocol == "" {
+		return "TCP"
+	}
+	return protocol
+}
+
+func portKey(port int64, protocol string) string {
+	return fmt.Sprintf("%d/%s", port, protocol)
+}
@@ -0,0 +1,238 @@
+package coordinator
+
+import (
+	"testing"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestAssertValidContainers(t *testing.T) {
+	tests := []struct {
+		name       string
+		param      api.RunParam
+		shouldFail bool
+	}{
+		{
+			name: "init container and sidecar",
+			param: api.RunParam{
+				Name:           "app-name",
+				Image:          "image:latest",
+				Port:           8086,
+				InitContainers: []api.Container{{Name: "migrate", Image: "migrate:latest", Ports: []api.ContainerPort{{Port: 8086}}}},
+				Sidecars:       []api.Container{{Name: "shipper", Image: "shipper:latest", Ports: []api.ContainerPort{{Name: "metrics", Port: 9090}}}},
+			},
+		},
+		{
+			name: "sidecar named after worker",
+			param: api.RunParam{
+				Name:     "app-name",
+				Image:    "image:latest",
+				Sidecars: []api.Container{{Name: "app-name", Image: "shipper:latest"}},
+			},
+			shouldFail: true,
+		},
+		{
+			name: "duplicate init and sidecar names",
+			param: api.RunParam{
+				Name:           "app-name",
+				Image:          "image:latest",
+				InitContainers: []api.Container{{Name: "helper", Image: "helper:latest"}},
+				Sidecars:       []api.Container{{Name: "helper", Image: "helper:latest"}},
+			},
+			shouldFail: true,
+		},
+		{
+			name: "sidecar port collides with worker port",
+			param: api.RunParam{
+				Name:     "app-name",
+				Image:    "image:latest",
+				Port:     8086,
+				Sidecars: []api.Container{{Name: "shipper", Image: "shipper:latest", Ports: []api.ContainerPort{{Port: 8086}}}},
+			},
+			shouldFail: true,
+		},
+		{
+			name: "sidecar ports collide",
+			param: api.RunParam{
+				Name:  "app-name",
+				Image: "image:latest",
+				Sidecars: []api.Container{
+					{Name: "one", Image: "one:latest", Ports: []api.ContainerPort{{Port: 9090, Protocol: "UDP"}}},
+					{Name: "two", Image: "two:latest", Ports: []api.ContainerPort{{Port: 9090, Protocol: "UDP"}}},
+				},
+			},
+			shouldFail: true,
+		},
+		{
+			name: "sidecar missing image",
+			param: api.RunParam{
+				Name:     "app-name",
+				Image:    "image:latest",
+				Sidecars: []api.Container{{Name: "shipper"}},
+			},
+			shouldFail: true,
+		},
+		{
+			name: "worker ports",
+			param: api.RunParam{
+				Name:  "app-name",
+				Image: "image:latest",
+				Port:  8086,
+				Ports: []api.ContainerPort{{Name: "metrics", Port: 9090}, {Name: "gossip", Port: 7946, Protocol: "UDP"}},
+			},
+		},
+		{
+			name: "unnamed worker port",
+			param: api.RunParam{
+				Name:  "app-name",
+				Image: "image:latest",
+				Ports: []api.ContainerPort{{Port: 9090}},
+			},
+			shouldFail: true,
+		},
+		{
+			name: "worker port named after shorthand",
+			param: api.RunParam{
+				Name:  "app-name",
+				Image: "image:latest",
+				Port:  8086,
+				Ports: []api.ContainerPort{{Name: "api", Port: 9090}},
+			},
+			shouldFail: true,
+		},
+		{
+			name: "duplicate worker port names",
+			param: api.RunParam{
+				Name:  "app-name",
+				Image: "image:latest",
+				Ports: []api.ContainerPort{{Name: "metrics", Port: 9090}, {Name: "metrics", Port: 9091}},
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid worker port name",
+			param: api.RunParam{
+				Name:  "app-name",
+				Image: "image:latest",
+				Ports: []api.ContainerPort{{Name: "metrics_port", Port: 9090}},
+			},
+			shouldFail: true,
+		},
+		{
+			name: "worker port used by sidecar",
+			param: api.RunParam{
+				Name:     "app-name",
+				Image:    "image:latest",
+				Ports:    []api.ContainerPort{{Name: "metrics", Port: 9090}},
+				Sidecars: []api.Container{{Name: "shipper", Image: "shipper:latest", Ports: []api.ContainerPort{{Port: 9090}}}},
+			},
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := assertValidRunParam(test.param)
+			if test.shouldFail && err == nil {
+				t.Error("expecting validation error")
+			}
+			if !test.shouldFail && err != nil {
+				t.Error("unexpected error:", err)
+			}
+		})
+	}
+}
+
+func TestGenerateContainers(t *testing.T) {
+	coord := &appCoordinator{name: "test-coord"}
+	param := api.RunParam{
+		Namespace: "appns",
+		Name:      "app-name",
+		Image:     "image:latest",
+		InitContainers: []api.Container{
+			{Name: "migrate", Image: "migrate:latest", Command: []string{"migrate"}, Args: []string{"--up"}},
+		},
+		Sidecars: []api.Container{
+			{Name: "shipper", Image: "shipper:latest", Envs: []string{"TARGET=logs"}, Ports: []api.ContainerPort{{Name: "metrics", Port: 9090}}},
+		},
+	}
+	deployment := coord.generateDeployment(param)
+
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	if len(containers) != 2 {
+		t.Fatal("unexpected container count:", len(containers))
+	}
+	sidecar := containers[1].(map[string]interface{})
+	if sidecar["name"] != "shipper" || sidecar["image"] != "shipper:latest" {
+		t.Error("unexpected sidecar:", sidecar)
+	}
+	envs, _, _ := unstructured.NestedSlice(sidecar, "env")
+	if len(envs) != 1 || envs[0].(map[string]interface{})["name"] != "TARGET" {
+		t.Error("unexpected sidecar env:", envs)
+	}
+	ports, _, _ := unstructured.NestedSlice(sidecar, "ports")
+	if len(ports) != 1 {
+		t.Fatal("unexpected sidecar port count:", len(ports))
+	}
+	port := ports[0].(map[string]interface{})
+	if port["name"] != "metrics" || port["containerPort"] != int64(9090) || port["protocol"] != "TCP" {
+		t.Error("unexpected sidecar port:", port)
+	}
+
+	inits, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "initContainers")
+	if len(inits) != 1 {
+		t.Fatal("unexpected init container count:", len(inits))
+	}
+	init := inits[0].(map[string]interface{})
+	command, _, _ := unstructured.NestedStringSlice(init, "command")
+	args, _, _ := unstructured.NestedStringSlice(init, "args")
+	if len(command) != 1 || command[0] != "migrate" || len(args) != 1 || args[0] != "--up" {
+		t.Error("unexpected init container:", init)
+	}
+}
+
+func TestGenerateWorkerPorts(t *testing.T) {
+	coord := &appCoordinator{name: "test-coord"}
+	param := api.RunParam{
+		Namespace: "appns",
+		Name:      "app-name",
+		Image:     "image:latest",
+		Port:      8086,
+		Ports:     []api.ContainerPort{{Name: "metrics", Port: 9090}, {Name: "gossip", Port: 7946, Protocol: "UDP"}},
+		Service:   api.ServiceClusterIP,
+	}
+
+	deployment := coord.generateDeployment(param)
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	ports, _, _ := unstructured.NestedSlice(containers[0].(map[string]interface{}), "ports")
+	if len(ports) != 3 {
+		t.Fatal("unexpected worker port count:", len(ports))
+	}
+	for i, name := range []string{"api", "metrics", "gossip"} {
+		if port := ports[i].(map[string]interface{}); port["name"] != name {
+			t.Error("unexpected worker port:", port)
+		}
+	}
+
+	svcPorts, _, _ := unstructured.NestedSlice(coord.generateService(param).Object, "spec", "ports")
+	if len(svcPorts) != 3 {
+		t.Fatal("unexpected service port count:", len(svcPorts))
+	}
+	gossip := svcPorts[2].(map[string]interface{})
+	if gossip["name"] != "gossip" || gossip["port"] != int64(7946) || gossip["protocol"] != "UDP" {
+		t.Error("unexpected service port:", gossip)
+	}
+
+	// ports are surfaced on pod events
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	unstructured.SetNestedSlice(pod.Object, containers, "spec", "containers")
+	e := api.PodEvent{Ports: getPodPorts(pod)}
+	port, ok := e.Port("gossip")
+	if !ok || port.Port != 7946 || port.Protocol != "UDP" {
+		t.Error("unexpected pod event port:", port)
+	}
+	if _, ok := e.Port("unknown"); ok {
+		t.Error("unexpected unknown port")
+	}
+}
@@ -3,16 +3,20 @@ package coordinator
 import (
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/vladimirvivien/horizon/pkg/api"
 	"github.com/vladimirvivien/horizon/pkg/client"
 	"github.com/vladimirvivien/horizon/pkg/controller"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
 type appCoordinator struct {
@@ -23,8 +27,32 @@ type appCoordinator struct {
 	coordEventFunc  api.CoordEventFunc
 	podEventFunc    api.PodEventFunc
 	deployEventFunc api.DeploymentEventFunc
+	stsEventFunc    api.StatefulSetEventFunc
+	dsEventFunc     api.DaemonSetEventFunc
+	jobEventFunc    api.JobEventFunc
+	cronEventFunc   api.CronJobEventFunc
+
+	// Jobs created earlier were not scheduled while
+	// watched, see emitJobScheduled
+	jobsWatchedAt time.Time
+
+	// owner of the created objects, see discoverOwner
+	owner          *metav1.OwnerReference
+	ownerNamespace string
+
+	listenerMu      sync.RWMutex
+	deployListeners map[int]api.DeploymentEventFunc
+	objectListeners map[int]objectListener
+	nextListenerID  int
+
+	// resources with informers set up, see watch
+	watchMu sync.Mutex
+	watched map[schema.GroupVersionResource]bool
+	stopCh  <-chan struct{}
 }
 
+const defaultWaitTimeout = 2 * time.Minute
+
 func New(name string, namespace string, config *restclient.Config) (api.Coordinator, error) {
 	client, err := client.New(namespace, config)
 	if err != nil {
@@ -39,15 +67,61 @@ func newCoord(k8s *client.K8sClient) *appCoordinator {
 	factory := dynamicinformer.NewDynamicSharedInformerFactory(k8s.Interface(), time.Second*3)
 	// 1. setup informer/watcher for cluster wide resources (node, etc)
 	// 2. Register callbacks for cluster events
-	return &appCoordinator{k8sClient: k8s, informerFac: factory}
+	return &appCoordinator{
+		k8sClient:       k8s,
+		informerFac:     factory,
+		deployListeners: make(map[int]api.DeploymentEventFunc),
+		objectListeners: make(map[int]objectListener),
+		watched:         make(map[schema.GroupVersionResource]bool),
+	}
 }
 
 func (c *appCoordinator) Start(stopCh <-chan struct{}) error {
 	defer runtime.HandleCrash()
 
-	// setup informers
-	c.setupDeploymentInformer()
-	c.setupPodInformer()
+	if err := c.discoverOwner(); err != nil {
+		return err
+	}
+
+	// setup informers, the kinds other than Deployments are
+	// only watched once handled or run, see watch
+	resources := []schema.GroupVersionResource{api.DeploymentsResource, api.PodsResource}
+	if c.stsEventFunc != nil {
+		resources = append(resources, api.StatefulSetsResource)
+	}
+	if c.dsEventFunc != nil {
+		resources = append(resources, api.DaemonSetsResource)
+	}
+	// the Jobs scheduled by CronJobs are reported from the Job informer
+	if c.jobEventFunc != nil || c.cronEventFunc != nil {
+		resources = append(resources, api.JobsResource)
+	}
+	if c.cronEventFunc != nil {
+		resources = append(resources, api.CronJobsResource)
+	}
+	if err := c.startWatch(stopCh, resources); err != nil {
+		return err
+	}
+
+	if c.coordEventFunc != nil {
+		c.coordEventFunc(api.CoordEvent{Type: api.CoordEventStart})
+	}
+
+	return nil
+}
+
+// startWatch sets up the informers of resources, along with those
+// of the resources run before the coordinator was started, then
+// starts them and waits for their cache to sync
+func (c *appCoordinator) startWatch(stopCh <-chan struct{}, resources []schema.GroupVersionResource) error {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	for _, res := range resources {
+		if !c.watched[res] {
+			c.setupWatch(res)
+		}
+	}
+	c.stopCh = stopCh
 
 	// start factory
 	c.informerFac.Start(stopCh)
@@ -55,21 +129,55 @@ func (c *appCoordinator) Start(stopCh <-chan struct{}) error {
 	syncMap := c.informerFac.WaitForCacheSync(stopCh)
 
 	// validate all resources are sync'd
-	if !syncMap[api.DeploymentsResource] {
-		return fmt.Errorf("failed to sync resource %s", api.DeploymentsResource)
+	for res := range c.watched {
+		if !syncMap[res] {
+			return fmt.Errorf("failed to sync resource %s", res)
+		}
 	}
+	return nil
+}
 
-	if !syncMap[api.PodsResource] {
-		return fmt.Errorf("failed to sync resource %s", api.PodsResource)
+// watch sets up the informers of res, a workload or pod resource,
+// unless already set up. Once the coordinator is started, the new
+// informers are started and their cache synced.
+func (c *appCoordinator) watch(res schema.GroupVersionResource) error {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	if c.watched[res] {
+		return nil
 	}
-
-	if c.coordEventFunc != nil {
-		c.coordEventFunc(api.CoordEvent{Type: api.CoordEventStart})
+	c.setupWatch(res)
+	if c.stopCh == nil {
+		return nil
+	}
+	c.informerFac.Start(c.stopCh)
+	if !cache.WaitForCacheSync(c.stopCh, c.informerFac.ForResource(res).Informer().HasSynced) {
+		return fmt.Errorf("failed to sync resource %s", res)
 	}
-
 	return nil
 }
 
+// setupWatch registers the event handlers of res,
+// c.watchMu must be held
+func (c *appCoordinator) setupWatch(res schema.GroupVersionResource) {
+	switch res {
+	case api.DeploymentsResource:
+		c.setupDeploymentInformer()
+	case api.StatefulSetsResource:
+		c.setupStatefulSetInformer()
+	case api.DaemonSetsResource:
+		c.setupDaemonSetInformer()
+	case api.JobsResource:
+		c.setupJobInformer()
+	case api.CronJobsResource:
+		c.setupCronJobInformer()
+	case api.PodsResource:
+		c.setupPodInformer()
+	}
+	c.setupObjectWatch(res)
+	c.watched[res] = true
+}
+
 func (c *appCoordinator) OnCoordEvent(e api.CoordEventFunc) api.Coordinator {
 	c.coordEventFunc = e
 	return c
@@ -83,72 +191,135 @@ func (c *appCoordinator) OnDeploymentEvent(e api.DeploymentEventFunc) api.Coordi
 func (c *appCoordinator) setupDeploymentInformer() {
 	ctrl := controller.New(c.informerFac, api.DeploymentsResource)
 	ctrl.SetObjectAddedFunc(func(obj interface{}) {
-		if c.deployEventFunc != nil {
-			uObj, ok := obj.(*unstructured.Unstructured)
-			if !ok {
-				log.Println("unexpected type for object")
-				return
-			}
-			e := api.DeploymentEvent{
-				Type:          api.DeploymentEventNew,
-				Name:          uObj.GetName(),
-				Namespace:     uObj.GetNamespace(),
-				ReadyReplicas: getDeploymentReplicasField(uObj, "readyReplicas"),
-				Ready:         isDeploymentReady(uObj),
-				Source:        uObj,
-			}
-			c.deployEventFunc(e)
+		uObj, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			log.Println("unexpected type for object")
+			return
 		}
+		c.emitDeploymentEvent(newDeploymentEvent(api.DeploymentEventNew, uObj))
 	})
 
 	ctrl.SetObjectUpdatedFunc(func(old, new interface{}) {
-		if c.deployEventFunc != nil {
-			newOne := new.(*unstructured.Unstructured)
-			newResVer, ok, err := unstructured.NestedString(newOne.Object, "metadata", "resourceVersion")
-			if err != nil || !ok {
-				log.Println(err)
-				return
-			}
-			oldOne := old.(*unstructured.Unstructured)
-			oldResVer, ok, err := unstructured.NestedString(oldOne.Object, "metadata", "resourceVersion")
-			if err != nil || !ok {
-				log.Println(err)
-				return
-			}
+		newOne := new.(*unstructured.Unstructured)
+		newResVer, ok, err := unstructured.NestedString(newOne.Object, "metadata", "resourceVersion")
+		if err != nil || !ok {
+			log.Println(err)
+			return
+		}
+		oldOne := old.(*unstructured.Unstructured)
+		oldResVer, ok, err := unstructured.NestedString(oldOne.Object, "metadata", "resourceVersion")
+		if err != nil || !ok {
+			log.Println(err)
+			return
+		}
+
+		if newResVer != oldResVer {
+			c.emitDeploymentEvent(newDeploymentEvent(api.DeploymentEventUpdate, newOne))
 
-			if newResVer != oldResVer {
-				e := api.DeploymentEvent{
-					Type:          api.DeploymentEventUpdate,
-					Name:          newOne.GetName(),
-					Namespace:     newOne.GetNamespace(),
-					ReadyReplicas: getDeploymentReplicasField(newOne, "readyReplicas"),
-					Ready:         isDeploymentReady(newOne),
-					Source:        newOne,
-				}
-				c.deployEventFunc(e)
+			// report rollout transitions, and the progress of a
+			// rollout as the controller observes new generations
+			rollout := getDeploymentRolloutEventType(newOne)
+			if rollout != getDeploymentRolloutEventType(oldOne) || getObservedGeneration(newOne) != getObservedGeneration(oldOne) {
+				c.emitDeploymentEvent(newDeploymentEvent(rollout, newOne))
 			}
 		}
 	})
 
 	ctrl.SetObjectDeletedFunc(func(obj interface{}) {
-		if c.deployEventFunc != nil {
-			uObj, ok := obj.(*unstructured.Unstructured)
-			if !ok {
-				log.Println("unexpected type for object")
-				return
-			}
+		uObj, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			log.Println("unexpected type for object")
+			return
+		}
+		c.emitDeploymentEvent(newDeploymentEvent(api.DeploymentEventDelete, uObj))
+	})
+}
 
-			e := api.DeploymentEvent{
-				Type:          api.DeploymentEventDelete,
-				Name:          uObj.GetName(),
-				Namespace:     uObj.GetNamespace(),
-				ReadyReplicas: getDeploymentReplicasField(uObj, "readyReplicas"),
-				Ready:         isDeploymentReady(uObj),
-				Source:        uObj,
-			}
-			c.deployEventFunc(e)
+func newDeploymentEvent(eventType api.DeploymentEventType, obj *unstructured.Unstructured) api.DeploymentEvent {
+	replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	return api.DeploymentEvent{
+		Type:               eventType,
+		Name:               obj.GetName(),
+		Namespace:          obj.GetNamespace(),
+		Port:               getDeploymentPort(obj),
+		Replicas:           replicas,
+		ReadyReplicas:      getDeploymentReplicasField(obj, "readyReplicas"),
+		UpdatedReplicas:    getDeploymentReplicasField(obj, "updatedReplicas"),
+		AvailableReplicas:  getDeploymentReplicasField(obj, "availableReplicas"),
+		Generation:         obj.GetGeneration(),
+		ObservedGeneration: getObservedGeneration(obj),
+		Revision:           obj.GetAnnotations()[revisionAnnotation],
+		Conditions:         getConditions(obj),
+		ServiceDNS:         obj.GetAnnotations()[serviceAnnotation],
+		Ready:              isDeploymentReady(obj),
+		Source:             obj,
+	}
+}
+
+// emitDeploymentEvent hands e to the registered callback
+// and to the internal deployment listeners
+func (c *appCoordinator) emitDeploymentEvent(e api.DeploymentEvent) {
+	if c.deployEventFunc != nil {
+		c.deployEventFunc(e)
+	}
+	c.listenerMu.RLock()
+	defer c.listenerMu.RUnlock()
+	for _, fn := range c.deployListeners {
+		fn(e)
+	}
+}
+
+// addDeploymentListener registers fn to receive deployment events
+// alongside the user callback. The returned func removes fn.
+func (c *appCoordinator) addDeploymentListener(fn api.DeploymentEventFunc) func() {
+	c.listenerMu.Lock()
+	defer c.listenerMu.Unlock()
+	id := c.nextListenerID
+	c.nextListenerID++
+	c.deployListeners[id] = fn
+	return func() {
+		c.listenerMu.Lock()
+		defer c.listenerMu.Unlock()
+		delete(c.deployListeners, id)
+	}
+}
+
+// waitForDeployment blocks until cond holds for an event of the named
+// deployment or the timeout expires, and returns the matching event.
+// The informer cache is checked first so that a deployment already
+// satisfying cond returns right away.
+func (c *appCoordinator) waitForDeployment(namespace, name string, timeout time.Duration, cond func(api.DeploymentEvent) bool) (api.DeploymentEvent, error) {
+	if timeout == 0 {
+		timeout = defaultWaitTimeout
+	}
+	done := make(chan struct{})
+	var once sync.Once
+	var result api.DeploymentEvent
+	remove := c.addDeploymentListener(func(e api.DeploymentEvent) {
+		if e.Namespace == namespace && e.Name == name && cond(e) {
+			once.Do(func() {
+				result = e
+				close(done)
+			})
 		}
 	})
+	defer remove()
+
+	lister := c.informerFac.ForResource(api.DeploymentsResource).Lister().ByNamespace(namespace)
+	if obj, err := lister.Get(name); err == nil {
+		if uObj, ok := obj.(*unstructured.Unstructured); ok {
+			if e := newDeploymentEvent(api.DeploymentEventUpdate, uObj); cond(e) {
+				return e, nil
+			}
+		}
+	}
+
+	select {
+	case <-done:
+		return result, nil
+	case <-time.After(timeout):
+		return api.DeploymentEvent{}, fmt.Errorf("timed out waiting for deployment %s/%s", namespace, name)
+	}
 }
 
 func (c *appCoordinator) OnPodEvent(e api.PodEventFunc) api.Coordinator {
@@ -165,47 +336,37 @@ func (c *appCoordinator) setupPodInformer() {
 				log.Println("unexpected type for object")
 				return
 			}
-			e := api.PodEvent{
-				Type:      api.PodEventNew,
-				Name:      uObj.GetName(),
-				Namespace: uObj.GetNamespace(),
-			}
-			c.podEventFunc(e)
+			c.podEventFunc(c.newPodEvent(api.PodEventNew, uObj))
 		}
 	})
 
 	ctrl.SetObjectUpdatedFunc(func(old, new interface{}) {
-		if c.podEventFunc != nil {
-			newOne := new.(*unstructured.Unstructured)
-			newResVer, ok, err := unstructured.NestedString(newOne.Object, "metadata", "resourceVersion")
-			if err != nil || !ok {
-				log.Println(err)
-				return
-			}
-			oldOne := old.(*unstructured.Unstructured)
-			oldResVer, ok, err := unstructured.NestedString(oldOne.Object, "metadata", "resourceVersion")
-			if err != nil || !ok {
-				log.Println(err)
-				return
-			}
+		newOne := new.(*unstructured.Unstructured)
+		newResVer, ok, err := unstructured.NestedString(newOne.Object, "metadata", "resourceVersion")
+		if err != nil || !ok {
+			log.Println(err)
+			return
+		}
+		oldOne := old.(*unstructured.Unstructured)
+		oldResVer, ok, err := unstructured.NestedString(oldOne.Object, "metadata", "resourceVersion")
+		if err != nil || !ok {
+			log.Println(err)
+			return
+		}
 
-			// only trigger if obj different
-			if newResVer != oldResVer {
-				phase := getPodPhase(newOne)
-				running := (phase == "Running")
-				e := api.PodEvent{
-					Type:      api.PodEventUpdate,
-					Name:      newOne.GetName(),
-					Namespace: newOne.GetNamespace(),
-				}
-				if running {
-					log.Printf("Pod %s is running\n", e.Name)
-					e.HostIP = getPodHostIP(newOne)
-					e.PodIP = getPodIP(newOne)
-					e.Running = running
-				}
-				c.podEventFunc(e)
+		// only trigger if obj different
+		if newResVer == oldResVer {
+			return
+		}
+
+		c.emitOrdinalEvent(oldOne, newOne)
+
+		if c.podEventFunc != nil {
+			e := c.newPodEvent(api.PodEventUpdate, newOne)
+			if e.Running {
+				log.Printf("Pod %s is running\n", e.Name)
 			}
+			c.podEventFunc(e)
 		}
 	})
 
@@ -216,16 +377,7 @@ func (c *appCoordinator) setupPodInformer() {
 				log.Println("unexpected type for object")
 				return
 			}
-			phase := getPodPhase(uObj)
-			e := api.PodEvent{
-				Type:      api.PodEventDelete,
-				Name:      uObj.GetName(),
-				Namespace: uObj.GetNamespace(),
-				HostIP:    getPodHostIP(uObj),
-				PodIP:     getPodIP(uObj),
-				Running:   (phase == "Running"),
-			}
-			c.podEventFunc(e)
+			c.podEventFunc(c.newPodEvent(api.PodEventDelete, uObj))
 		}
 	})
 }
@@ -238,35 +390,109 @@ func getDeploymentReplicasField(obj *unstructured.Unstructured, field string) in
 	return reps
 }
 
+func getObservedGeneration(obj *unstructured.Unstructured) int64 {
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	return observed
+}
+
+// isDeploymentObserved returns true once the deployment controller
+// has observed the latest generation of the deployment spec
+func isDeploymentObserved(obj *unstructured.Unstructured) bool {
+	return getObservedGeneration(obj) >= obj.GetGeneration()
+}
+
 func isDeploymentReady(obj *unstructured.Unstructured) bool {
 	requestedReplicas := getDeploymentReplicasField(obj, "replicas")
 	readyReplicas := getDeploymentReplicasField(obj, "readyReplicas")
 	return readyReplicas == requestedReplicas
 }
 
-func getPodPhase(obj *unstructured.Unstructured) string {
-	phase, ok, err := unstructured.NestedString(obj.Object, "status", "phase")
-	if !ok || err != nil {
-		log.Println("failed to get phase from pod status, error:", err)
-		phase = "unknown"
+// getDeploymentPort returns the first port of the first container
+func getDeploymentPort(obj *unstructured.Unstructured) int64 {
+	containers, ok, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if !ok || err != nil || len(containers) == 0 {
+		return 0
+	}
+	container, ok := containers[0].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	ports, ok, err := unstructured.NestedSlice(container, "ports")
+	if !ok || err != nil || len(ports) == 0 {
+		return 0
+	}
+	port, ok := ports[0].(map[string]interface{})
+	if !ok {
+		return 0
 	}
-	return phase
+	val, _, _ := unstructured.NestedInt64(port, "containerPort")
+	return val
 }
 
-func getPodHostIP(obj *unstructured.Unstructured) string {
-	ip, ok, err := unstructured.NestedString(obj.Object, "status", "hostIP")
+// getConditions returns the status conditions of obj
+func getConditions(obj *unstructured.Unstructured) []api.Condition {
+	conds, ok, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
 	if !ok || err != nil {
-		log.Println("failed to get hostIP from pod status, error:", err)
-		ip = "unknown"
+		return nil
+	}
+	var result []api.Condition
+	for _, cond := range conds {
+		condMap, ok := cond.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		result = append(result, api.Condition{
+			Type:    getStringField(condMap, "type"),
+			Status:  getStringField(condMap, "status"),
+			Reason:  getStringField(condMap, "reason"),
+			Message: getStringField(condMap, "message"),
+		})
+	}
+	return result
+}
+
+// getDeploymentRolloutEventType reports the rollout state of the deployment
+// following the same rules as `kubectl rollout status`
+func getDeploymentRolloutEventType(obj *unstructured.Unstructured) api.DeploymentEventType {
+	if !isDeploymentObserved(obj) {
+		return api.DeploymentEventRolloutProgressing
+	}
+	for _, cond := range getConditions(obj) {
+		if cond.Type == "Progressing" && cond.Reason == "ProgressDeadlineExceeded" {
+			return api.DeploymentEventRolloutFailed
+		}
+	}
+	replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	updated := getDeploymentReplicasField(obj, "updatedReplicas")
+	switch {
+	case updated < replicas:
+		return api.DeploymentEventRolloutProgressing
+	case getDeploymentReplicasField(obj, "replicas") > updated:
+		return api.DeploymentEventRolloutProgressing
+	case getDeploymentReplicasField(obj, "availableReplicas") < updated:
+		return api.DeploymentEventRolloutProgressing
 	}
-	return ip
+	return api.DeploymentEventRolloutComplete
+}
+
+func getStringField(obj map[string]interface{}, field string) string {
+	val, _, _ := unstructured.NestedString(obj, field)
+	return val
 }
 
-func getPodIP(obj *unstructured.Unstructured) string {
-	ip, ok, err := unstructured.NestedString(obj.Object, "status", "podIP")
+func isPodReady(obj *unstructured.Unstructured) bool {
+	conds, ok, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
 	if !ok || err != nil {
-		log.Println("failed to get podIP from pod status, error:", err)
-		ip = "unknown"
+		return false
+	}
+	for _, cond := range conds {
+		condMap, ok := cond.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if getStringField(condMap, "type") == "Ready" {
+			return getStringField(condMap, "status") == "True"
+		}
 	}
-	return ip
+	return false
 }
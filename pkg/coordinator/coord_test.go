@@ -2,6 +2,7 @@ package coordinator
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -47,6 +48,44 @@ func TestCoordStart(t *testing.T) {
 	}
 }
 
+func TestCoordStart_WatchedResources(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.OnDeploymentEvent(func(api.DeploymentEvent) {})
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+
+	listed := func() map[string]bool {
+		result := make(map[string]bool)
+		for _, action := range fakeClient.Actions() {
+			if action.GetVerb() == "list" {
+				result[action.GetResource().Resource] = true
+			}
+		}
+		return result
+	}
+	for _, res := range []string{"replicasets", "statefulsets", "daemonsets", "jobs", "cronjobs"} {
+		if listed()[res] {
+			t.Error("unexpected informer started for", res)
+		}
+	}
+
+	// the informers of a kind are started once it is run
+	if err := coord.Run(api.RunParam{Kind: api.WorkloadJob, Namespace: "appns", Name: "batch", Image: "image:latest"}); err != nil {
+		t.Fatal(err)
+	}
+	if !listed()["jobs"] {
+		t.Error("expecting job informer to be started")
+	}
+	if listed()["cronjobs"] {
+		t.Error("unexpected informer started for cronjobs")
+	}
+}
+
 func TestCoordDeploy(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -111,13 +150,19 @@ func TestCoordDeploy_WithPodEvent(t *testing.T) {
 			name:     "simple deployment",
 			runParam: api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:latest"},
 			coordFunc: func(ch chan struct{}, client *fake.FakeDynamicClient, coord api.Coordinator, param api.RunParam) {
+				var created, running sync.Once
 				coord.OnDeploymentEvent(func(e api.DeploymentEvent) {
-					// simulate pod deployment from Deployment
-					pod := generateTestPod("app-name", "appns", "image:latest")
-					_, err := client.Resource(podsResource).Namespace("appns").Create(pod, metav1.CreateOptions{})
-					if err != nil {
-						t.Error(err)
-					}
+					// simulate pod deployment from Deployment, the pod
+					// is scheduled and started once created
+					created.Do(func() {
+						pod := generateTestPod("app-name", "appns", "image:latest")
+						pod.SetResourceVersion("1")
+						unstructured.SetNestedField(pod.Object, map[string]interface{}{"phase": "Pending"}, "status")
+						_, err := client.Resource(api.PodsResource).Namespace("appns").Create(pod, metav1.CreateOptions{})
+						if err != nil {
+							t.Error(err)
+						}
+					})
 				})
 
 				coord.OnPodEvent(func(e api.PodEvent) {
@@ -127,13 +172,26 @@ func TestCoordDeploy_WithPodEvent(t *testing.T) {
 					if e.Namespace != "appns" {
 						t.Error("unexpected pod namespace:", e.Namespace)
 					}
-					if e.HostIP != "192.168.176.128" {
-						t.Error("unexpected pod host IP value:", e.HostIP)
-					}
-					if e.PodIP != "172.17.0.8" {
-						t.Error("unexpected pod ip value:", e.PodIP)
+					switch e.Type {
+					case api.PodEventNew:
+						pod := generateTestPod("app-name", "appns", "image:latest")
+						pod.SetResourceVersion("2")
+						_, err := client.Resource(api.PodsResource).Namespace("appns").Update(pod, metav1.UpdateOptions{})
+						if err != nil {
+							t.Error(err)
+						}
+					case api.PodEventUpdate:
+						if !e.Running {
+							return
+						}
+						if e.HostIP != "192.168.176.128" {
+							t.Error("unexpected pod host IP value:", e.HostIP)
+						}
+						if e.PodIP != "172.17.0.8" {
+							t.Error("unexpected pod ip value:", e.PodIP)
+						}
+						running.Do(func() { close(ch) })
 					}
-					close(ch)
 				})
 
 				if err := coord.Start(ch); err != nil {
@@ -0,0 +1,231 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/controller"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func (c *appCoordinator) OnCronJobEvent(e api.CronJobEventFunc) api.Coordinator {
+	c.cronEventFunc = e
+	return c
+}
+
+// Suspend suspends or resumes the schedule of a managed CronJob
+func (c *appCoordinator) Suspend(param api.SuspendParam) error {
+	if param.Name == "" {
+		return errors.New("missing cronjob name")
+	}
+
+	cl := c.k8sClient.Interface().Resource(api.CronJobsResource).Namespace(param.Namespace)
+	existing, err := cl.Get(param.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if !c.isManaged(existing) {
+		return &api.ConflictError{
+			Kind:        existing.GetKind(),
+			Namespace:   param.Namespace,
+			Name:        param.Name,
+			Coordinator: c.name,
+		}
+	}
+
+	patch := []byte(fmt.Sprintf(`{"spec":{"suspend":%t}}`, param.Suspend))
+	_, err = cl.Patch(param.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// validateCronJob validates the CronJob specific fields of param
+func validateCronJob(param api.RunParam) field.ErrorList {
+	var errs field.ErrorList
+	if param.Kind != api.WorkloadCronJob {
+		if param.Schedule != "" {
+			errs = append(errs, field.Forbidden(field.NewPath("schedule"), "only supported by CronJobs"))
+		}
+		if param.ConcurrencyPolicy != "" {
+			errs = append(errs, field.Forbidden(field.NewPath("concurrencyPolicy"), "only supported by CronJobs"))
+		}
+		if param.Suspend {
+			errs = append(errs, field.Forbidden(field.NewPath("suspend"), "only supported by CronJobs"))
+		}
+		if param.SuccessfulJobsHistoryLimit != nil {
+			errs = append(errs, field.Forbidden(field.NewPath("successfulJobsHistoryLimit"), "only supported by CronJobs"))
+		}
+		if param.FailedJobsHistoryLimit != nil {
+			errs = append(errs, field.Forbidden(field.NewPath("failedJobsHistoryLimit"), "only supported by CronJobs"))
+		}
+		return errs
+	}
+
+	errs = append(errs, validateSchedule(field.NewPath("schedule"), param.Schedule)...)
+	switch param.ConcurrencyPolicy {
+	case "", "Allow", "Forbid", "Replace":
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("concurrencyPolicy"), param.ConcurrencyPolicy, []string{"Allow", "Forbid", "Replace"}))
+	}
+	if param.SuccessfulJobsHistoryLimit != nil && *param.SuccessfulJobsHistoryLimit < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("successfulJobsHistoryLimit"), *param.SuccessfulJobsHistoryLimit, "must not be negative"))
+	}
+	if param.FailedJobsHistoryLimit != nil && *param.FailedJobsHistoryLimit < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("failedJobsHistoryLimit"), *param.FailedJobsHistoryLimit, "must not be negative"))
+	}
+	if param.Service != api.ServiceNone {
+		errs = append(errs, field.Forbidden(field.NewPath("service"), "not supported by CronJobs"))
+	}
+	return errs
+}
+
+// validateSchedule checks the shape of a cron schedule: either five
+// fields or a predefined schedule such as @hourly. Field values are
+// left for the API server to validate.
+func validateSchedule(fldPath *field.Path, schedule string) field.ErrorList {
+	if schedule == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	if strings.HasPrefix(schedule, "@") {
+		switch schedule {
+		case "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly":
+			return nil
+		}
+		if strings.HasPrefix(schedule, "@every ") {
+			if _, err := time.ParseDuration(strings.TrimPrefix(schedule, "@every ")); err == nil {
+				return nil
+			}
+		}
+		return field.ErrorList{field.Invalid(fldPath, schedule, "unknown descriptor")}
+	}
+	if len(strings.Fields(schedule)) != 5 {
+		return field.ErrorList{field.Invalid(fldPath, schedule, "expecting 5 fields")}
+	}
+	return nil
+}
+
+func (c *appCoordinator) generateCronJob(param api.RunParam) *unstructured.Unstructured {
+	job := c.generateJob(param)
+	spec := map[string]interface{}{
+		"schedule": param.Schedule,
+		"suspend":  param.Suspend,
+		"jobTemplate": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"labels": c.generateLabels(param),
+			},
+			"spec": job.Object["spec"],
+		},
+	}
+	if param.ConcurrencyPolicy != "" {
+		spec["concurrencyPolicy"] = param.ConcurrencyPolicy
+	}
+	if param.SuccessfulJobsHistoryLimit != nil {
+		spec["successfulJobsHistoryLimit"] = *param.SuccessfulJobsHistoryLimit
+	}
+	if param.FailedJobsHistoryLimit != nil {
+		spec["failedJobsHistoryLimit"] = *param.FailedJobsHistoryLimit
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "CronJob",
+			"metadata":   c.generateObjectMeta(param),
+			"spec":       spec,
+		},
+	}
+}
+
+func (c *appCoordinator) setupCronJobInformer() {
+	ctrl := controller.New(c.informerFac, api.CronJobsResource)
+	ctrl.SetObjectAddedFunc(func(obj interface{}) {
+		if c.cronEventFunc != nil {
+			uObj, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				log.Println("unexpected type for object")
+				return
+			}
+			c.cronEventFunc(newCronJobEvent(api.CronJobEventNew, uObj))
+		}
+	})
+
+	ctrl.SetObjectUpdatedFunc(func(old, new interface{}) {
+		if c.cronEventFunc != nil {
+			newOne := new.(*unstructured.Unstructured)
+			oldOne := old.(*unstructured.Unstructured)
+			if newOne.GetResourceVersion() == oldOne.GetResourceVersion() {
+				return
+			}
+			c.cronEventFunc(newCronJobEvent(api.CronJobEventUpdate, newOne))
+		}
+	})
+
+	ctrl.SetObjectDeletedFunc(func(obj interface{}) {
+		if c.cronEventFunc != nil {
+			uObj, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				log.Println("unexpected type for object")
+				return
+			}
+			c.cronEventFunc(newCronJobEvent(api.CronJobEventDelete, uObj))
+		}
+	})
+}
+
+// emitJobScheduled emits CronJobEventJobScheduled when job was spawned,
+// since the Jobs are watched, by a CronJob managed by this coordinator.
+// The Jobs listed when the informer starts were scheduled earlier.
+func (c *appCoordinator) emitJobScheduled(job *unstructured.Unstructured) {
+	if c.cronEventFunc == nil || !c.isManaged(job) {
+		return
+	}
+	if job.GetCreationTimestamp().Time.Before(c.jobsWatchedAt) {
+		return
+	}
+	owner := metav1.GetControllerOf(job)
+	if owner == nil || owner.Kind != "CronJob" {
+		return
+	}
+
+	lister := c.informerFac.ForResource(api.CronJobsResource).Lister().ByNamespace(job.GetNamespace())
+	obj, err := lister.Get(owner.Name)
+	if err != nil {
+		log.Printf("failed to get cronjob %s/%s: %s", job.GetNamespace(), owner.Name, err)
+		return
+	}
+	cronJob, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		log.Println("unexpected type for object")
+		return
+	}
+	e := newCronJobEvent(api.CronJobEventJobScheduled, cronJob)
+	e.Job = job.GetName()
+	c.cronEventFunc(e)
+}
+
+func newCronJobEvent(eventType api.CronJobEventType, obj *unstructured.Unstructured) api.CronJobEvent {
+	schedule, _, _ := unstructured.NestedString(obj.Object, "spec", "schedule")
+	suspend, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend")
+	active, _, _ := unstructured.NestedSlice(obj.Object, "status", "active")
+	e := api.CronJobEvent{
+		Type:      eventType,
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		Schedule:  schedule,
+		Suspend:   suspend,
+		Active:    int64(len(active)),
+		Source:    obj,
+	}
+	if last, ok, _ := unstructured.NestedString(obj.Object, "status", "lastScheduleTime"); ok {
+		if t, err := time.Parse(time.RFC3339, last); err == nil {
+			e.LastScheduleTime = t
+		}
+	}
+	return e
+}
@@ -0,0 +1,211 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestCronJobRun(t *testing.T) {
+	coord := &appCoordinator{name: "test-coord"}
+	history := int64(1)
+	param := api.RunParam{
+		Kind:                       api.WorkloadCronJob,
+		Namespace:                  "appns",
+		Name:                       "compaction",
+		Image:                      "image:latest",
+		Schedule:                   "0 2 * * *",
+		ConcurrencyPolicy:          "Forbid",
+		SuccessfulJobsHistoryLimit: &history,
+		FailedJobsHistoryLimit:     &history,
+		ActiveDeadlineSeconds:      3600,
+	}
+	if err := assertValidRunParam(param); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cronJob := coord.generateWorkload(param)
+	if cronJob.GetKind() != "CronJob" {
+		t.Fatal("unexpected workload kind:", cronJob.GetKind())
+	}
+	if schedule, _, _ := unstructured.NestedString(cronJob.Object, "spec", "schedule"); schedule != "0 2 * * *" {
+		t.Error("unexpected schedule:", schedule)
+	}
+	if policy, _, _ := unstructured.NestedString(cronJob.Object, "spec", "concurrencyPolicy"); policy != "Forbid" {
+		t.Error("unexpected concurrency policy:", policy)
+	}
+	if limit, _, _ := unstructured.NestedInt64(cronJob.Object, "spec", "failedJobsHistoryLimit"); limit != 1 {
+		t.Error("unexpected failed jobs history limit:", limit)
+	}
+	if deadline, _, _ := unstructured.NestedInt64(cronJob.Object, "spec", "jobTemplate", "spec", "activeDeadlineSeconds"); deadline != 3600 {
+		t.Error("unexpected job deadline:", deadline)
+	}
+	if policy, _, _ := unstructured.NestedString(cronJob.Object, "spec", "jobTemplate", "spec", "template", "spec", "restartPolicy"); policy != "Never" {
+		t.Error("unexpected restart policy:", policy)
+	}
+	labels, _, _ := unstructured.NestedStringMap(cronJob.Object, "spec", "jobTemplate", "metadata", "labels")
+	if labels["coordinator"] != "test-coord" {
+		t.Error("spawned jobs missing coordinator label:", labels)
+	}
+}
+
+func TestCronJobValidation(t *testing.T) {
+	negative := int64(-1)
+	tests := []struct {
+		name       string
+		param      api.RunParam
+		shouldFail bool
+	}{
+		{
+			name:  "predefined schedule",
+			param: api.RunParam{Kind: api.WorkloadCronJob, Schedule: "@hourly"},
+		},
+		{
+			name:  "every schedule",
+			param: api.RunParam{Kind: api.WorkloadCronJob, Schedule: "@every 15m"},
+		},
+		{
+			name:       "missing schedule",
+			param:      api.RunParam{Kind: api.WorkloadCronJob},
+			shouldFail: true,
+		},
+		{
+			name:       "short schedule",
+			param:      api.RunParam{Kind: api.WorkloadCronJob, Schedule: "0 2 * *"},
+			shouldFail: true,
+		},
+		{
+			name:       "unknown descriptor",
+			param:      api.RunParam{Kind: api.WorkloadCronJob, Schedule: "@fortnightly"},
+			shouldFail: true,
+		},
+		{
+			name:       "bad concurrency policy",
+			param:      api.RunParam{Kind: api.WorkloadCronJob, Schedule: "@daily", ConcurrencyPolicy: "Never"},
+			shouldFail: true,
+		},
+		{
+			name:       "negative history limit",
+			param:      api.RunParam{Kind: api.WorkloadCronJob, Schedule: "@daily", FailedJobsHistoryLimit: &negative},
+			shouldFail: true,
+		},
+		{
+			name:       "schedule on deployment",
+			param:      api.RunParam{Schedule: "@daily"},
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := validateCronJob(test.param)
+			if test.shouldFail && len(errs) == 0 {
+				t.Fatal("expecting failure, got none")
+			}
+			if !test.shouldFail && len(errs) > 0 {
+				t.Fatal(errs.ToAggregate())
+			}
+		})
+	}
+}
+
+func TestCronJobSuspend(t *testing.T) {
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	param := api.RunParam{Kind: api.WorkloadCronJob, Namespace: "appns", Name: "compaction", Image: "image:latest", Schedule: "@daily"}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	if err := coord.Suspend(api.SuspendParam{Namespace: "appns", Name: "compaction", Suspend: true}); err != nil {
+		t.Fatal(err)
+	}
+	cronJob, err := fakeClient.Resource(api.CronJobsResource).Namespace("appns").Get("compaction", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suspend, _, _ := unstructured.NestedBool(cronJob.Object, "spec", "suspend"); !suspend {
+		t.Error("cronjob not suspended")
+	}
+
+	// objects not managed by the coordinator are left alone
+	param.Name = "other"
+	unmanaged := coord.generateCronJob(param)
+	unmanaged.SetLabels(map[string]string{"app": "other"})
+	if _, err := fakeClient.Resource(api.CronJobsResource).Namespace("appns").Create(unmanaged, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = coord.Suspend(api.SuspendParam{Namespace: "appns", Name: "other", Suspend: true})
+	if !api.IsConflictError(err) {
+		t.Fatal("expecting conflict error, got:", err)
+	}
+}
+
+func TestCronJobScheduledEvent(t *testing.T) {
+	timeout := time.Duration(3 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	owner := &appCoordinator{name: "test-coord"}
+	param := api.RunParam{Kind: api.WorkloadCronJob, Namespace: "appns", Name: "compaction", Image: "image:latest", Schedule: "@daily"}
+	cronJob := owner.generateCronJob(param)
+	cronJob.SetUID("cronjob-uid")
+
+	// simulate the cronjob controller spawning a job
+	generateScheduledJob := func(name string, created time.Time) *unstructured.Unstructured {
+		job := owner.generateJob(param)
+		job.SetName(name)
+		job.SetCreationTimestamp(metav1.NewTime(created))
+		controller := true
+		job.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: "batch/v1",
+			Kind:       "CronJob",
+			Name:       cronJob.GetName(),
+			UID:        cronJob.GetUID(),
+			Controller: &controller,
+		}})
+		return job
+	}
+
+	// jobs scheduled before the coordinator started are not reported
+	earlier := generateScheduledJob("compaction-1553990400", time.Now().Add(-time.Hour))
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme(), cronJob, earlier)
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	eventCh := make(chan api.CronJobEvent, 10)
+	coord.OnCronJobEvent(func(e api.CronJobEvent) {
+		eventCh <- e
+	})
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+
+	job := generateScheduledJob("compaction-1554076800", time.Now())
+	if _, err := fakeClient.Resource(api.JobsResource).Namespace("appns").Create(job, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		select {
+		case e := <-eventCh:
+			if e.Type != api.CronJobEventJobScheduled {
+				continue
+			}
+			if e.Name != "compaction" || e.Job != "compaction-1554076800" || e.Schedule != "@daily" {
+				t.Error("unexpected scheduled event:", e.Name, e.Job, e.Schedule)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("cronjob scheduled event not received")
+		}
+	}
+}
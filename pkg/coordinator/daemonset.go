@@ -0,0 +1,159 @@
+package coordinator
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/controller"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func (c *appCoordinator) OnDaemonSetEvent(e api.DaemonSetEventFunc) api.Coordinator {
+	c.dsEventFunc = e
+	return c
+}
+
+// validateDaemonSet validates the DaemonSet specific fields of param
+func validateDaemonSet(param api.RunParam) field.ErrorList {
+	var errs field.ErrorList
+	if param.Kind != api.WorkloadDaemonSet {
+		if param.UpdateStrategy != "" {
+			errs = append(errs, field.Forbidden(field.NewPath("updateStrategy"), "only supported by DaemonSets"))
+		}
+		if param.MaxUnavailable != "" {
+			errs = append(errs, field.Forbidden(field.NewPath("maxUnavailable"), "only supported by DaemonSets"))
+		}
+		return errs
+	}
+
+	if param.Replicas != 0 {
+		errs = append(errs, field.Forbidden(field.NewPath("replicas"), "DaemonSets run one pod per node"))
+	}
+	switch param.UpdateStrategy {
+	case "", "RollingUpdate":
+	case "OnDelete":
+		if param.MaxUnavailable != "" {
+			errs = append(errs, field.Forbidden(field.NewPath("maxUnavailable"), "requires the RollingUpdate strategy"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("updateStrategy"), param.UpdateStrategy, []string{"RollingUpdate", "OnDelete"}))
+	}
+	if param.MaxUnavailable != "" {
+		if _, err := parseIntOrPercent(param.MaxUnavailable); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("maxUnavailable"), param.MaxUnavailable, err.Error()))
+		}
+	}
+	return errs
+}
+
+// parseIntOrPercent returns val as an int64 or,
+// for percentages such as "25%", as a string
+func parseIntOrPercent(val string) (interface{}, error) {
+	num := strings.TrimSuffix(val, "%")
+	i, err := strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return nil, errors.New("must be a number or a percentage")
+	}
+	if i < 0 {
+		return nil, errors.New("must not be negative")
+	}
+	if num != val {
+		return val, nil
+	}
+	return i, nil
+}
+
+func (c *appCoordinator) generateDaemonSet(param api.RunParam) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": c.generateSelector(param),
+		},
+		"template": c.generatePodTemplate(param),
+	}
+	if param.UpdateStrategy != "" || param.MaxUnavailable != "" {
+		strategy := map[string]interface{}{
+			"type": "RollingUpdate",
+		}
+		if param.UpdateStrategy != "" {
+			strategy["type"] = param.UpdateStrategy
+		}
+		if param.MaxUnavailable != "" {
+			maxUnavailable, _ := parseIntOrPercent(param.MaxUnavailable)
+			strategy["rollingUpdate"] = map[string]interface{}{
+				"maxUnavailable": maxUnavailable,
+			}
+		}
+		spec["updateStrategy"] = strategy
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "DaemonSet",
+			"metadata":   c.generateObjectMeta(param),
+			"spec":       spec,
+		},
+	}
+}
+
+func (c *appCoordinator) setupDaemonSetInformer() {
+	ctrl := controller.New(c.informerFac, api.DaemonSetsResource)
+	ctrl.SetObjectAddedFunc(func(obj interface{}) {
+		if c.dsEventFunc != nil {
+			uObj, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				log.Println("unexpected type for object")
+				return
+			}
+			c.dsEventFunc(newDaemonSetEvent(api.DaemonSetEventNew, uObj))
+		}
+	})
+
+	ctrl.SetObjectUpdatedFunc(func(old, new interface{}) {
+		if c.dsEventFunc != nil {
+			newOne := new.(*unstructured.Unstructured)
+			oldOne := old.(*unstructured.Unstructured)
+			if newOne.GetResourceVersion() != oldOne.GetResourceVersion() {
+				c.dsEventFunc(newDaemonSetEvent(api.DaemonSetEventUpdate, newOne))
+			}
+		}
+	})
+
+	ctrl.SetObjectDeletedFunc(func(obj interface{}) {
+		if c.dsEventFunc != nil {
+			uObj, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				log.Println("unexpected type for object")
+				return
+			}
+			c.dsEventFunc(newDaemonSetEvent(api.DaemonSetEventDelete, uObj))
+		}
+	})
+}
+
+func newDaemonSetEvent(eventType api.DaemonSetEventType, obj *unstructured.Unstructured) api.DaemonSetEvent {
+	return api.DaemonSetEvent{
+		Type:                   eventType,
+		Name:                   obj.GetName(),
+		Namespace:              obj.GetNamespace(),
+		DesiredNumberScheduled: getDeploymentReplicasField(obj, "desiredNumberScheduled"),
+		CurrentNumberScheduled: getDeploymentReplicasField(obj, "currentNumberScheduled"),
+		UpdatedNumberScheduled: getDeploymentReplicasField(obj, "updatedNumberScheduled"),
+		NumberReady:            getDeploymentReplicasField(obj, "numberReady"),
+		NumberAvailable:        getDeploymentReplicasField(obj, "numberAvailable"),
+		Ready:                  isDaemonSetReady(obj),
+		Source:                 obj,
+	}
+}
+
+// isDaemonSetReady compares ready pods to scheduled nodes
+// the same way isDeploymentReady compares replicas
+func isDaemonSetReady(obj *unstructured.Unstructured) bool {
+	desired := getDeploymentReplicasField(obj, "desiredNumberScheduled")
+	ready := getDeploymentReplicasField(obj, "numberReady")
+	return ready == desired
+}
@@ -0,0 +1,179 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestDaemonSetRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		param      api.RunParam
+		test       func(*testing.T, *unstructured.Unstructured)
+		shouldFail bool
+	}{
+		{
+			name: "node selector and rolling update",
+			param: api.RunParam{
+				NodeSelector:   map[string]string{"node-role.kubernetes.io/worker": "", "disk": "ssd"},
+				MaxUnavailable: "25%",
+			},
+			test: func(t *testing.T, ds *unstructured.Unstructured) {
+				selector, _, _ := unstructured.NestedStringMap(ds.Object, "spec", "template", "spec", "nodeSelector")
+				if len(selector) != 2 || selector["disk"] != "ssd" {
+					t.Error("unexpected node selector:", selector)
+				}
+				strategy, _, _ := unstructured.NestedString(ds.Object, "spec", "updateStrategy", "type")
+				if strategy != "RollingUpdate" {
+					t.Error("unexpected update strategy:", strategy)
+				}
+				max, _, _ := unstructured.NestedString(ds.Object, "spec", "updateStrategy", "rollingUpdate", "maxUnavailable")
+				if max != "25%" {
+					t.Error("unexpected max unavailable:", max)
+				}
+				if _, ok, _ := unstructured.NestedFieldNoCopy(ds.Object, "spec", "replicas"); ok {
+					t.Error("daemonsets should not carry replicas")
+				}
+			},
+		},
+		{
+			name:  "max unavailable count",
+			param: api.RunParam{MaxUnavailable: "2"},
+			test: func(t *testing.T, ds *unstructured.Unstructured) {
+				max, _, _ := unstructured.NestedInt64(ds.Object, "spec", "updateStrategy", "rollingUpdate", "maxUnavailable")
+				if max != 2 {
+					t.Error("unexpected max unavailable:", max)
+				}
+			},
+		},
+		{
+			name:  "on delete",
+			param: api.RunParam{UpdateStrategy: "OnDelete"},
+			test: func(t *testing.T, ds *unstructured.Unstructured) {
+				strategy, _, _ := unstructured.NestedString(ds.Object, "spec", "updateStrategy", "type")
+				if strategy != "OnDelete" {
+					t.Error("unexpected update strategy:", strategy)
+				}
+			},
+		},
+		{
+			name:       "on delete with max unavailable",
+			param:      api.RunParam{UpdateStrategy: "OnDelete", MaxUnavailable: "1"},
+			shouldFail: true,
+		},
+		{
+			name:       "bad max unavailable",
+			param:      api.RunParam{MaxUnavailable: "half"},
+			shouldFail: true,
+		},
+		{
+			name:       "bad strategy",
+			param:      api.RunParam{UpdateStrategy: "Recreate"},
+			shouldFail: true,
+		},
+		{
+			name:       "replicas",
+			param:      api.RunParam{Replicas: 3},
+			shouldFail: true,
+		},
+		{
+			name:       "bad node selector",
+			param:      api.RunParam{NodeSelector: map[string]string{"-disk": "ssd"}},
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+			coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+			coord.name = "test-coord"
+
+			param := test.param
+			param.Kind = api.WorkloadDaemonSet
+			param.Namespace = "appns"
+			param.Name = "agent"
+			param.Image = "image:latest"
+			err := coord.Run(param)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expecting failure, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ds, err := fakeClient.Resource(api.DaemonSetsResource).Namespace("appns").Get("agent", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.test(t, ds)
+		})
+	}
+}
+
+func TestDaemonSetEvents(t *testing.T) {
+	timeout := time.Duration(3 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	eventCh := make(chan api.DaemonSetEvent, 10)
+	coord.OnDaemonSetEvent(func(e api.DaemonSetEvent) {
+		eventCh <- e
+	})
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+	if err := coord.Run(api.RunParam{Kind: api.WorkloadDaemonSet, Namespace: "appns", Name: "agent", Image: "image:latest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cl := fakeClient.Resource(api.DaemonSetsResource).Namespace("appns")
+	ds, err := cl.Get("agent", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds.SetResourceVersion("2")
+	ds.Object["status"] = map[string]interface{}{
+		"desiredNumberScheduled": int64(3),
+		"currentNumberScheduled": int64(3),
+		"updatedNumberScheduled": int64(3),
+		"numberReady":            int64(3),
+		"numberAvailable":        int64(2),
+	}
+	if _, err := cl.Update(ds, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		select {
+		case e := <-eventCh:
+			if e.Type != api.DaemonSetEventUpdate {
+				continue
+			}
+			if e.DesiredNumberScheduled != 3 || e.NumberReady != 3 || e.NumberAvailable != 2 {
+				t.Error("unexpected counts:", e.DesiredNumberScheduled, e.NumberReady, e.NumberAvailable)
+			}
+			if !e.Ready {
+				t.Error("expecting daemonset to be ready")
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("daemonset update event not received")
+		}
+	}
+}
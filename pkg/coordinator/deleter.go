@@ -0,0 +1,163 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func (c *appCoordinator) Delete(param api.DeleteParam) error {
+	if err := assertValidDeleteParam(param); err != nil {
+		return err
+	}
+	policy := param.Policy
+	if policy == "" {
+		policy = api.DeleteBackground
+	}
+
+	res, err := workloadResource(param.Kind)
+	if err != nil {
+		return err
+	}
+	deleted, err := c.deleteManaged(res, param.Namespace, param.Name, policy)
+	if err != nil {
+		return err
+	}
+
+	// remove the companion objects, if any
+	for _, res := range companionResources(deleted) {
+		if err := c.deleteCompanion(res, param.Namespace, param.Name); err != nil {
+			return err
+		}
+	}
+
+	if !param.Wait {
+		return nil
+	}
+	return c.waitForPodsGone(param)
+}
+
+// deleteManaged deletes the named object if it carries the
+// coordinator=<name> label of this coordinator, and returns it
+func (c *appCoordinator) deleteManaged(res schema.GroupVersionResource, namespace, name string, policy api.DeletePolicy) (*unstructured.Unstructured, error) {
+	cl := c.k8sClient.Interface().Resource(res).Namespace(namespace)
+	existing, err := cl.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if existing.GetLabels()["coordinator"] != c.name {
+		return nil, &api.ConflictError{
+			Kind:        existing.GetKind(),
+			Namespace:   namespace,
+			Name:        name,
+			Coordinator: c.name,
+		}
+	}
+
+	// guard against deleting an object recreated since the Get
+	uid := existing.GetUID()
+	propagation := metav1.DeletionPropagation(policy)
+	opts := &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+		Preconditions:     &metav1.Preconditions{UID: &uid},
+	}
+	if err := cl.Delete(name, opts); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// companionResources returns the resources of the companion objects
+// recorded on the workload obj, which may be nil, in deletion order
+func companionResources(obj *unstructured.Unstructured) []schema.GroupVersionResource {
+	if obj == nil {
+		return nil
+	}
+	annotations := obj.GetAnnotations()
+	var resources []schema.GroupVersionResource
+	if _, ok := annotations[serviceAnnotation]; ok {
+		resources = append(resources, api.ServicesResource)
+	}
+	if _, ok := annotations[roleAnnotation]; ok {
+		resources = append(resources, api.RoleBindingsResource, api.RolesResource)
+	}
+	if _, ok := annotations[serviceAccountAnnotation]; ok {
+		resources = append(resources, api.ServiceAccountsResource)
+	}
+	return resources
+}
+
+// deleteStaleCompanions deletes the companion objects recorded on
+// previous, the workload before it was updated, that are no longer
+// recorded on desired
+func (c *appCoordinator) deleteStaleCompanions(param api.RunParam, previous, desired *unstructured.Unstructured) error {
+	wanted := make(map[schema.GroupVersionResource]bool)
+	for _, res := range companionResources(desired) {
+		wanted[res] = true
+	}
+	for _, res := range companionResources(previous) {
+		if wanted[res] {
+			continue
+		}
+		if err := c.deleteCompanion(res, param.Namespace, param.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteCompanion deletes the named object created alongside a
+// workload, if it exists and is managed by this coordinator
+func (c *appCoordinator) deleteCompanion(res schema.GroupVersionResource, namespace, name string) error {
+	_, err := c.deleteManaged(res, namespace, name, api.DeleteBackground)
+	if err != nil && !apierrors.IsNotFound(err) && !api.IsConflictError(err) {
+		return err
+	}
+	return nil
+}
+
+func assertValidDeleteParam(param api.DeleteParam) error {
+	if param.Name == "" {
+		return errors.New("missing deployment name")
+	}
+	switch param.Policy {
+	case "", api.DeleteBackground, api.DeleteForeground:
+	case api.DeleteOrphan:
+		if param.Wait {
+			return errors.New("cannot wait for pod termination when orphaning pods")
+		}
+	default:
+		return fmt.Errorf("invalid delete policy %q: must be one of Background, Foreground, Orphan", param.Policy)
+	}
+	return nil
+}
+
+// waitForPodsGone polls the pod informer cache until no pods
+// selected by the workload labels remain.
+func (c *appCoordinator) waitForPodsGone(param api.DeleteParam) error {
+	timeout := param.Timeout
+	if timeout == 0 {
+		timeout = defaultWaitTimeout
+	}
+	lister := c.informerFac.ForResource(api.PodsResource).Lister().ByNamespace(param.Namespace)
+	selector := c.selectorLabels(param.Name).AsSelector()
+
+	err := wait.PollImmediate(100*time.Millisecond, timeout, func() (bool, error) {
+		pods, err := lister.List(selector)
+		if err != nil {
+			return false, err
+		}
+		return len(pods) == 0, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for pods of %s/%s to terminate", param.Namespace, param.Name)
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestDeleter(t *testing.T) {
+	tests := []struct {
+		name       string
+		owner      string
+		param      api.DeleteParam
+		withPod    bool
+		shouldFail bool
+	}{
+		{
+			name:  "delete managed deployment",
+			owner: "test-coord",
+			param: api.DeleteParam{Namespace: "appns", Name: "app-name", Policy: api.DeleteForeground},
+		},
+		{
+			name:    "delete and wait for pods",
+			owner:   "test-coord",
+			param:   api.DeleteParam{Namespace: "appns", Name: "app-name", Wait: true, Timeout: 2 * time.Second},
+			withPod: true,
+		},
+		{
+			name:       "refuse unmanaged deployment",
+			owner:      "other-coord",
+			param:      api.DeleteParam{Namespace: "appns", Name: "app-name"},
+			shouldFail: true,
+		},
+		{
+			name:       "refuse wait with orphan policy",
+			owner:      "test-coord",
+			param:      api.DeleteParam{Namespace: "appns", Name: "app-name", Policy: api.DeleteOrphan, Wait: true},
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timeout := time.Duration(3 * time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			owner := &appCoordinator{name: test.owner}
+			deployment := owner.generateDeployment(api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:latest", Replicas: 1})
+			objs := []runtime.Object{deployment}
+			if test.withPod {
+				pod := generateTestPod("app-name-abc", "appns", "image:latest")
+				pod.SetLabels(owner.selectorLabels("app-name"))
+				objs = append(objs, pod)
+			}
+
+			fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme(), objs...)
+			coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+			coord.name = "test-coord"
+			if err := coord.Start(ctx.Done()); err != nil {
+				t.Fatal(err)
+			}
+
+			// simulate pod termination after deletion,
+			// waited for before the test returns
+			podErr := make(chan error, 1)
+			if test.withPod {
+				go func() {
+					time.Sleep(300 * time.Millisecond)
+					podErr <- fakeClient.Resource(api.PodsResource).Namespace("appns").Delete("app-name-abc", &metav1.DeleteOptions{})
+				}()
+			} else {
+				podErr <- nil
+			}
+
+			err := coord.Delete(test.param)
+			if err := <-podErr; err != nil {
+				t.Fatal(err)
+			}
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expecting delete to fail")
+				}
+				if _, err := fakeClient.Resource(api.DeploymentsResource).Namespace("appns").Get("app-name", metav1.GetOptions{}); err != nil {
+					t.Error("deployment should not have been deleted:", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = fakeClient.Resource(api.DeploymentsResource).Namespace("appns").Get("app-name", metav1.GetOptions{})
+			if !errors.IsNotFound(err) {
+				t.Error("expecting deployment to be deleted, got:", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package coordinator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/controller"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func (c *appCoordinator) OnJobEvent(e api.JobEventFunc) api.Coordinator {
+	c.jobEventFunc = e
+	return c
+}
+
+// validateJob validates the Job specific fields of param
+func validateJob(param api.RunParam) field.ErrorList {
+	var errs field.ErrorList
+	if param.Kind != api.WorkloadJob && param.Kind != api.WorkloadCronJob {
+		if param.Completions != 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("completions"), "only supported by Jobs and CronJobs"))
+		}
+		if param.Parallelism != 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("parallelism"), "only supported by Jobs and CronJobs"))
+		}
+		if param.BackoffLimit != nil {
+			errs = append(errs, field.Forbidden(field.NewPath("backoffLimit"), "only supported by Jobs and CronJobs"))
+		}
+		if param.ActiveDeadlineSeconds != 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("activeDeadlineSeconds"), "only supported by Jobs and CronJobs"))
+		}
+		return errs
+	}
+
+	if param.Replicas != 0 {
+		errs = append(errs, field.Forbidden(field.NewPath("replicas"), "Jobs run completions pods, parallelism at a time"))
+	}
+	if param.Completions < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("completions"), param.Completions, "must not be negative"))
+	}
+	if param.Parallelism < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("parallelism"), param.Parallelism, "must not be negative"))
+	}
+	if param.BackoffLimit != nil && *param.BackoffLimit < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("backoffLimit"), *param.BackoffLimit, "must not be negative"))
+	}
+	if param.ActiveDeadlineSeconds < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("activeDeadlineSeconds"), param.ActiveDeadlineSeconds, "must not be negative"))
+	}
+	return errs
+}
+
+func (c *appCoordinator) generateJob(param api.RunParam) *unstructured.Unstructured {
+	template := c.generatePodTemplate(param)
+	// failed pods are replaced and counted against the backoff limit
+	unstructured.SetNestedField(template, "Never", "spec", "restartPolicy")
+
+	spec := map[string]interface{}{
+		"template": template,
+	}
+	if param.Completions > 0 {
+		spec["completions"] = param.Completions
+	}
+	if param.Parallelism > 0 {
+		spec["parallelism"] = param.Parallelism
+	}
+	if param.BackoffLimit != nil {
+		spec["backoffLimit"] = *param.BackoffLimit
+	}
+	if param.ActiveDeadlineSeconds > 0 {
+		spec["activeDeadlineSeconds"] = param.ActiveDeadlineSeconds
+	}
+
+	meta := c.generateObjectMeta(param)
+	unstructured.SetNestedField(meta, generateSpecHash(spec), "annotations", specHashAnnotation)
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"metadata":   meta,
+			"spec":       spec,
+		},
+	}
+}
+
+// specHashAnnotation records the hash of the spec a Job was created
+// with. The Job controller adds its own labels to the pod template and
+// the API server defaults the spec, so a Job run again is compared
+// through the hash rather than field by field.
+const specHashAnnotation = "coordinator/spec-hash"
+
+func generateSpecHash(spec map[string]interface{}) string {
+	// maps are marshaled with sorted keys
+	data, _ := json.Marshal(spec)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// assertJobUnchanged returns a *api.JobChangedError unless the Job
+// existing was created with the spec of desired
+func assertJobUnchanged(existing, desired *unstructured.Unstructured) error {
+	if existing.GetAnnotations()[specHashAnnotation] != desired.GetAnnotations()[specHashAnnotation] {
+		return &api.JobChangedError{Namespace: existing.GetNamespace(), Name: existing.GetName()}
+	}
+	return nil
+}
+
+func (c *appCoordinator) setupJobInformer() {
+	// creation timestamps have a precision of a second
+	c.jobsWatchedAt = time.Now().Truncate(time.Second)
+	ctrl := controller.New(c.informerFac, api.JobsResource)
+	ctrl.SetObjectAddedFunc(func(obj interface{}) {
+		uObj, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			log.Println("unexpected type for object")
+			return
+		}
+		c.emitJobScheduled(uObj)
+		if c.jobEventFunc != nil {
+			c.jobEventFunc(newJobEvent(api.JobEventNew, uObj))
+			if state := getJobState(uObj); state != api.JobEventUnknown {
+				c.jobEventFunc(newJobEvent(state, uObj))
+			}
+		}
+	})
+
+	ctrl.SetObjectUpdatedFunc(func(old, new interface{}) {
+		if c.jobEventFunc != nil {
+			newOne := new.(*unstructured.Unstructured)
+			oldOne := old.(*unstructured.Unstructured)
+			if newOne.GetResourceVersion() == oldOne.GetResourceVersion() {
+				return
+			}
+			c.jobEventFunc(newJobEvent(api.JobEventUpdate, newOne))
+			if state := getJobState(newOne); state != getJobState(oldOne) {
+				c.jobEventFunc(newJobEvent(state, newOne))
+			}
+		}
+	})
+
+	ctrl.SetObjectDeletedFunc(func(obj interface{}) {
+		if c.jobEventFunc != nil {
+			uObj, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				log.Println("unexpected type for object")
+				return
+			}
+			c.jobEventFunc(newJobEvent(api.JobEventDelete, uObj))
+		}
+	})
+}
+
+func newJobEvent(eventType api.JobEventType, obj *unstructured.Unstructured) api.JobEvent {
+	e := api.JobEvent{
+		Type:      eventType,
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		Active:    getDeploymentReplicasField(obj, "active"),
+		Succeeded: getDeploymentReplicasField(obj, "succeeded"),
+		Failed:    getDeploymentReplicasField(obj, "failed"),
+		Source:    obj,
+	}
+	if cond, ok := getJobCondition(obj, "Failed"); ok {
+		e.Reason = cond.Reason
+		e.Message = cond.Message
+	}
+	return e
+}
+
+// getJobState returns JobEventSucceeded or JobEventFailed for a finished job,
+// JobEventStarted for a running job and JobEventUnknown otherwise
+func getJobState(obj *unstructured.Unstructured) api.JobEventType {
+	if _, ok := getJobCondition(obj, "Complete"); ok {
+		return api.JobEventSucceeded
+	}
+	if _, ok := getJobCondition(obj, "Failed"); ok {
+		return api.JobEventFailed
+	}
+	if _, ok, _ := unstructured.NestedString(obj.Object, "status", "startTime"); ok {
+		return api.JobEventStarted
+	}
+	return api.JobEventUnknown
+}
+
+// getJobCondition returns the condition of the given type if its status is True
+func getJobCondition(obj *unstructured.Unstructured, condType string) (api.Condition, bool) {
+	for _, cond := range getConditions(obj) {
+		if cond.Type == condType && cond.Status == "True" {
+			return cond, true
+		}
+	}
+	return api.Condition{}, false
+}
@@ -0,0 +1,174 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestJobRun(t *testing.T) {
+	coord := &appCoordinator{name: "test-coord"}
+	backoff := int64(0)
+	param := api.RunParam{
+		Kind:                  api.WorkloadJob,
+		Namespace:             "appns",
+		Name:                  "migrate",
+		Image:                 "image:latest",
+		Completions:           4,
+		Parallelism:           2,
+		BackoffLimit:          &backoff,
+		ActiveDeadlineSeconds: 600,
+	}
+	if err := assertValidRunParam(param); err != nil {
+		t.Fatal(err)
+	}
+
+	_, job := coord.generateWorkload(param)
+	if job.GetKind() != "Job" || job.GetAPIVersion() != "batch/v1" {
+		t.Fatal("unexpected workload:", job.GetAPIVersion(), job.GetKind())
+	}
+	fields := map[string]int64{
+		"completions":           4,
+		"parallelism":           2,
+		"backoffLimit":          0,
+		"activeDeadlineSeconds": 600,
+	}
+	for field, expected := range fields {
+		val, ok, _ := unstructured.NestedInt64(job.Object, "spec", field)
+		if !ok || val != expected {
+			t.Errorf("unexpected %s: %d", field, val)
+		}
+	}
+	if policy, _, _ := unstructured.NestedString(job.Object, "spec", "template", "spec", "restartPolicy"); policy != "Never" {
+		t.Error("unexpected restart policy:", policy)
+	}
+	if _, ok, _ := unstructured.NestedFieldNoCopy(job.Object, "spec", "replicas"); ok {
+		t.Error("jobs should not carry replicas")
+	}
+
+	if err := assertValidRunParam(api.RunParam{Name: "app", Image: "image:latest", Completions: 2}); err == nil {
+		t.Error("expecting job settings on deployment to be rejected")
+	}
+}
+
+func TestJobRunAgain(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+
+	param := api.RunParam{Kind: api.WorkloadJob, Namespace: "appns", Name: "migrate", Image: "image:v1"}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+
+	// the Job controller labels the pod template
+	cl := fakeClient.Resource(api.JobsResource).Namespace("appns")
+	job, err := cl.Get("migrate", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unstructured.SetNestedField(job.Object, "job-uid", "spec", "template", "metadata", "labels", "controller-uid")
+	unstructured.SetNestedField(job.Object, "migrate", "spec", "template", "metadata", "labels", "job-name")
+	if _, err := cl.Update(job, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	fakeClient.ClearActions()
+	if err := coord.Run(param); err != nil {
+		t.Fatal("unexpected error running the same job again:", err)
+	}
+	for _, action := range fakeClient.Actions() {
+		if action.GetResource() == api.JobsResource && action.GetVerb() != "get" && action.GetVerb() != "list" && action.GetVerb() != "watch" {
+			t.Error("unexpected job action:", action.GetVerb())
+		}
+	}
+
+	param.Image = "image:v2"
+	if err := coord.Run(param); !api.IsJobChangedError(err) {
+		t.Fatal("expecting job changed error, got:", err)
+	}
+}
+
+func TestJobEvents(t *testing.T) {
+	timeout := time.Duration(3 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	eventCh := make(chan api.JobEvent, 10)
+	coord.OnJobEvent(func(e api.JobEvent) {
+		eventCh <- e
+	})
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+	if err := coord.Run(api.RunParam{Kind: api.WorkloadJob, Namespace: "appns", Name: "migrate", Image: "image:latest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	updateStatus := func(version string, status map[string]interface{}) {
+		cl := fakeClient.Resource(api.JobsResource).Namespace("appns")
+		job, err := cl.Get("migrate", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		job.SetResourceVersion(version)
+		job.Object["status"] = status
+		if _, err := cl.Update(job, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectEvent := func(eventType api.JobEventType) api.JobEvent {
+		for {
+			select {
+			case e := <-eventCh:
+				if e.Type == eventType {
+					return e
+				}
+			case <-ctx.Done():
+				t.Fatal("job event not received:", eventType)
+			}
+		}
+	}
+
+	expectEvent(api.JobEventNew)
+
+	updateStatus("2", map[string]interface{}{"startTime": "2019-04-01T00:00:00Z", "active": int64(1)})
+	if e := expectEvent(api.JobEventStarted); e.Active != 1 {
+		t.Error("unexpected active count:", e.Active)
+	}
+
+	updateStatus("3", map[string]interface{}{
+		"startTime": "2019-04-01T00:00:00Z",
+		"failed":    int64(3),
+		"conditions": []interface{}{
+			map[string]interface{}{
+				"type":    "Failed",
+				"status":  "True",
+				"reason":  "BackoffLimitExceeded",
+				"message": "Job has reached the specified backoff limit",
+			},
+		},
+	})
+	e := expectEvent(api.JobEventFailed)
+	if e.Failed != 3 || e.Reason != "BackoffLimitExceeded" {
+		t.Error("unexpected failed event:", e.Failed, e.Reason)
+	}
+}
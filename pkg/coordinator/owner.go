@@ -0,0 +1,96 @@
+package coordinator
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PodNameEnv and PodNamespaceEnv name the environment variables,
+// set through the downward API, from which the coordinator finds
+// the pod it runs in
+const (
+	PodNameEnv      = "POD_NAME"
+	PodNamespaceEnv = "POD_NAMESPACE"
+)
+
+// ownerResources maps the kinds followed from the coordinator
+// pod to its top-most controller
+var ownerResources = map[string]schema.GroupVersionResource{
+	"ReplicaSet":  api.ReplicaSetsResource,
+	"Deployment":  api.DeploymentsResource,
+	"StatefulSet": api.StatefulSetsResource,
+	"DaemonSet":   api.DaemonSetsResource,
+	"Job":         api.JobsResource,
+	"CronJob":     api.CronJobsResource,
+}
+
+// discoverOwner looks up the pod the coordinator runs in and walks
+// its controller references up to the top-most object, typically the
+// supervisor Deployment, which then owns every object created in its
+// namespace. Nothing is owned when the coordinator runs out of cluster.
+func (c *appCoordinator) discoverOwner() error {
+	name, namespace := os.Getenv(PodNameEnv), os.Getenv(PodNamespaceEnv)
+	if name == "" || namespace == "" {
+		return nil
+	}
+
+	cl := c.k8sClient.Interface()
+	obj, err := cl.Resource(api.PodsResource).Namespace(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get coordinator pod %s/%s: %s", namespace, name, err)
+	}
+	for {
+		ref := metav1.GetControllerOf(obj)
+		if ref == nil {
+			break
+		}
+		res, ok := ownerResources[ref.Kind]
+		if !ok || schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind).Group != res.Group {
+			break
+		}
+		parent, err := cl.Resource(res).Namespace(namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get coordinator owner %s %s/%s: %s", ref.Kind, namespace, ref.Name, err)
+		}
+		obj = parent
+	}
+
+	c.owner = &metav1.OwnerReference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+	}
+	c.ownerNamespace = namespace
+	return nil
+}
+
+func (c *appCoordinator) ownerUID() types.UID {
+	if c.owner == nil {
+		return ""
+	}
+	return c.owner.UID
+}
+
+// generateOwnerReferences returns the owner references of the objects
+// created for param, or nil when they are not owned. Owners must live in
+// the same namespace, the garbage collector deletes objects whose owner
+// cannot be found in theirs.
+func (c *appCoordinator) generateOwnerReferences(param api.RunParam) []interface{} {
+	if c.owner == nil || param.Detached || param.Namespace != c.ownerNamespace {
+		return nil
+	}
+	return []interface{}{
+		map[string]interface{}{
+			"apiVersion": c.owner.APIVersion,
+			"kind":       c.owner.Kind,
+			"name":       c.owner.Name,
+			"uid":        string(c.owner.UID),
+		},
+	}
+}
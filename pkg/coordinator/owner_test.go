@@ -0,0 +1,138 @@
+package coordinator
+
+import (
+	"os"
+	"testing"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func newOwnedObject(apiVersion, kind, name, uid string, controller *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata": map[string]interface{}{
+				"namespace": "default",
+				"name":      name,
+				"uid":       uid,
+			},
+		},
+	}
+	if controller != nil {
+		isController := true
+		obj.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: controller.GetAPIVersion(),
+			Kind:       controller.GetKind(),
+			Name:       controller.GetName(),
+			UID:        controller.GetUID(),
+			Controller: &isController,
+		}})
+	}
+	return obj
+}
+
+func TestOwnerReferences(t *testing.T) {
+	super := newOwnedObject("apps/v1", "Deployment", "super", "super-uid", nil)
+	rs := newOwnedObject("apps/v1", "ReplicaSet", "super-5c4f", "rs-uid", super)
+	pod := newOwnedObject("v1", "Pod", "super-5c4f-x2v", "pod-uid", rs)
+
+	os.Setenv(PodNameEnv, pod.GetName())
+	os.Setenv(PodNamespaceEnv, pod.GetNamespace())
+	defer os.Unsetenv(PodNameEnv)
+	defer os.Unsetenv(PodNamespaceEnv)
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme(), super, rs, pod)
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+	if err := coord.discoverOwner(); err != nil {
+		t.Fatal(err)
+	}
+	if coord.owner == nil || coord.owner.Kind != "Deployment" || coord.owner.UID != "super-uid" {
+		t.Fatalf("unexpected owner: %#v", coord.owner)
+	}
+
+	ownerUIDs := func(obj *unstructured.Unstructured) []string {
+		var uids []string
+		for _, ref := range obj.GetOwnerReferences() {
+			uids = append(uids, string(ref.UID))
+		}
+		return uids
+	}
+
+	param := api.RunParam{
+		Namespace: "default",
+		Name:      "worker",
+		Image:     "image:latest",
+		Port:      8080,
+		Service:   api.ServiceClusterIP,
+	}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	deploy, err := fakeClient.Resource(api.DeploymentsResource).Namespace("default").Get("worker", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uids := ownerUIDs(deploy); len(uids) != 1 || uids[0] != "super-uid" {
+		t.Error("unexpected deployment owners:", uids)
+	}
+	svc, err := fakeClient.Resource(api.ServicesResource).Namespace("default").Get("worker", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uids := ownerUIDs(svc); len(uids) != 1 || uids[0] != "super-uid" {
+		t.Error("unexpected service owners:", uids)
+	}
+
+	// owners set by others survive detaching
+	refs := append(deploy.GetOwnerReferences(), metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "other-uid"})
+	deploy.SetOwnerReferences(refs)
+	deploy.SetResourceVersion("2")
+	if _, err := fakeClient.Resource(api.DeploymentsResource).Namespace("default").Update(deploy, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	param.Detached = true
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	deploy, err = fakeClient.Resource(api.DeploymentsResource).Namespace("default").Get("worker", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uids := ownerUIDs(deploy); len(uids) != 1 || uids[0] != "other-uid" {
+		t.Error("unexpected detached deployment owners:", uids)
+	}
+
+	// owners are not set across namespaces
+	param = api.RunParam{Namespace: "appns", Name: "worker", Image: "image:latest"}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	deploy, err = fakeClient.Resource(api.DeploymentsResource).Namespace("appns").Get("worker", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uids := ownerUIDs(deploy); len(uids) != 0 {
+		t.Error("unexpected owners across namespaces:", uids)
+	}
+}
+
+func TestOwnerReferences_OutOfCluster(t *testing.T) {
+	os.Unsetenv(PodNameEnv)
+	os.Unsetenv(PodNamespaceEnv)
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	if err := coord.discoverOwner(); err != nil {
+		t.Fatal(err)
+	}
+	if coord.owner != nil {
+		t.Fatalf("unexpected owner: %#v", coord.owner)
+	}
+}
@@ -0,0 +1,152 @@
+package coordinator
+
+import (
+	"strings"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func (c *appCoordinator) newPodEvent(eventType api.PodEventType, obj *unstructured.Unstructured) api.PodEvent {
+	hostIP, _, _ := unstructured.NestedString(obj.Object, "status", "hostIP")
+	podIP, _, _ := unstructured.NestedString(obj.Object, "status", "podIP")
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	nodeName, _, _ := unstructured.NestedString(obj.Object, "spec", "nodeName")
+	ownerKind, ownerName := c.getPodOwner(obj)
+	return api.PodEvent{
+		Type:              eventType,
+		Name:              obj.GetName(),
+		Namespace:         obj.GetNamespace(),
+		HostIP:            hostIP,
+		PodIP:             podIP,
+		Running:           phase == "Running",
+		Phase:             phase,
+		Ready:             isPodReady(obj),
+		Conditions:        getConditions(obj),
+		ContainerStatuses: getPodContainerStatuses(obj),
+		Ports:             getPodPorts(obj),
+		NodeName:          nodeName,
+		Labels:            obj.GetLabels(),
+		OwnerKind:         ownerKind,
+		OwnerName:         ownerName,
+		Source:            obj,
+	}
+}
+
+// getPodOwner returns the kind and name of the controller of the pod.
+// For a ReplicaSet managed by a Deployment, the Deployment is returned.
+func (c *appCoordinator) getPodOwner(obj *unstructured.Unstructured) (string, string) {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil {
+		return "", ""
+	}
+	if ref.Kind != "ReplicaSet" {
+		return ref.Kind, ref.Name
+	}
+
+	if rs, err := c.getReplicaSet(obj.GetNamespace(), ref.Name); err == nil {
+		if rsRef := metav1.GetControllerOf(rs); rsRef != nil && rsRef.Kind == "Deployment" {
+			return rsRef.Kind, rsRef.Name
+		}
+		return ref.Kind, ref.Name
+	}
+
+	// the ReplicaSet cannot be read, Deployments name theirs
+	// after themselves and the pod template hash
+	if hash := obj.GetLabels()["pod-template-hash"]; hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+		return "Deployment", strings.TrimSuffix(ref.Name, "-"+hash)
+	}
+	return ref.Kind, ref.Name
+}
+
+// getReplicaSet returns the named ReplicaSet from the informer cache,
+// or from the API server until the cache holds it. The informer is
+// only started once a pod owned by a ReplicaSet is seen.
+func (c *appCoordinator) getReplicaSet(namespace, name string) (*unstructured.Unstructured, error) {
+	if informer := c.replicaSetInformer(); informer != nil && informer.HasSynced() {
+		obj, err := c.informerFac.ForResource(api.ReplicaSetsResource).Lister().ByNamespace(namespace).Get(name)
+		if rs, ok := obj.(*unstructured.Unstructured); err == nil && ok {
+			return rs, nil
+		}
+	}
+	return c.k8sClient.Interface().Resource(api.ReplicaSetsResource).Namespace(namespace).Get(name, metav1.GetOptions{})
+}
+
+// replicaSetInformer starts the ReplicaSet informer, without waiting
+// for its cache to sync, and returns it. Nil is returned until the
+// coordinator is started.
+func (c *appCoordinator) replicaSetInformer() cache.SharedIndexInformer {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	if c.stopCh == nil {
+		return nil
+	}
+	informer := c.informerFac.ForResource(api.ReplicaSetsResource).Informer()
+	if !c.watched[api.ReplicaSetsResource] {
+		c.watched[api.ReplicaSetsResource] = true
+		c.informerFac.Start(c.stopCh)
+	}
+	return informer
+}
+
+// getPodContainerStatuses returns the statuses of the init
+// containers followed by those of the containers of the pod
+func getPodContainerStatuses(obj *unstructured.Unstructured) []api.ContainerStatus {
+	var result []api.ContainerStatus
+	for _, field := range []string{"initContainerStatuses", "containerStatuses"} {
+		statuses, _, _ := unstructured.NestedSlice(obj.Object, "status", field)
+		for _, s := range statuses {
+			status, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			ready, _, _ := unstructured.NestedBool(status, "ready")
+			restarts, _, _ := unstructured.NestedInt64(status, "restartCount")
+			cs := api.ContainerStatus{
+				Name:         getStringField(status, "name"),
+				Init:         field == "initContainerStatuses",
+				Ready:        ready,
+				RestartCount: restarts,
+			}
+			state, _, _ := unstructured.NestedMap(status, "state")
+			switch {
+			case state["running"] != nil:
+				cs.State = "Running"
+			case state["waiting"] != nil:
+				cs.State = "Waiting"
+				cs.Reason, _, _ = unstructured.NestedString(state, "waiting", "reason")
+			case state["terminated"] != nil:
+				cs.State = "Terminated"
+				cs.Reason, _, _ = unstructured.NestedString(state, "terminated", "reason")
+			}
+			result = append(result, cs)
+		}
+	}
+	return result
+}
+
+// getPodPorts returns the ports declared by the containers of the pod
+func getPodPorts(obj *unstructured.Unstructured) []api.ContainerPort {
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "containers")
+	var ports []api.ContainerPort
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		containerPorts, _, _ := unstructured.NestedSlice(container, "ports")
+		for _, p := range containerPorts {
+			port, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			number, _, _ := unstructured.NestedInt64(port, "containerPort")
+			name, _, _ := unstructured.NestedString(port, "name")
+			protocol, _, _ := unstructured.NestedString(port, "protocol")
+			ports = append(ports, api.ContainerPort{Name: name, Port: number, Protocol: protocolOrDefault(protocol)})
+		}
+	}
+	return ports
+}
@@ -0,0 +1,140 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func generateTestReplicaSetPod(rsName string) *unstructured.Unstructured {
+	pod := generateTestPod(rsName+"-x2v", "appns", "image:latest")
+	pod.SetLabels(map[string]string{"app": "app-name", "pod-template-hash": "5c4f"})
+	isController := true
+	pod.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "apps/v1",
+		Kind:       "ReplicaSet",
+		Name:       rsName,
+		UID:        "rs-uid",
+		Controller: &isController,
+	}})
+	unstructured.SetNestedField(pod.Object, "node-1", "spec", "nodeName")
+	unstructured.SetNestedSlice(pod.Object, []interface{}{
+		map[string]interface{}{"type": "Ready", "status": "False", "reason": "ContainersNotReady"},
+	}, "status", "conditions")
+	unstructured.SetNestedSlice(pod.Object, []interface{}{
+		map[string]interface{}{
+			"name":         "app-name",
+			"ready":        false,
+			"restartCount": int64(3),
+			"state": map[string]interface{}{
+				"waiting": map[string]interface{}{"reason": "CrashLoopBackOff"},
+			},
+		},
+	}, "status", "containerStatuses")
+	return pod
+}
+
+func TestPodEvent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	isController := true
+	rs := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "ReplicaSet",
+		"metadata": map[string]interface{}{
+			"namespace": "appns",
+			"name":      "rs-name",
+			"uid":       "rs-uid",
+		},
+	}}
+	rs.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       "app-name",
+		UID:        "deploy-uid",
+		Controller: &isController,
+	}})
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme(), rs)
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	eventCh := make(chan api.PodEvent, 1)
+	coord.OnPodEvent(func(e api.PodEvent) {
+		eventCh <- e
+	})
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the ReplicaSet name does not follow the hash convention,
+	// so the owner can only be found through the ReplicaSet
+	pod := generateTestReplicaSetPod("rs-name")
+	if _, err := fakeClient.Resource(api.PodsResource).Namespace("appns").Create(pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var e api.PodEvent
+	select {
+	case e = <-eventCh:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for pod event")
+	}
+	if e.Type != api.PodEventNew || e.Phase != "Running" || !e.Running || e.Ready {
+		t.Errorf("unexpected pod state: %s %s %t %t", e.Name, e.Phase, e.Running, e.Ready)
+	}
+	if e.NodeName != "node-1" || e.Labels["app"] != "app-name" || e.Source == nil {
+		t.Errorf("unexpected pod event: %#v", e)
+	}
+	if e.OwnerKind != "Deployment" || e.OwnerName != "app-name" {
+		t.Error("unexpected pod owner:", e.OwnerKind, e.OwnerName)
+	}
+	if len(e.Conditions) != 1 || e.Conditions[0].Reason != "ContainersNotReady" {
+		t.Error("unexpected pod conditions:", e.Conditions)
+	}
+	if len(e.ContainerStatuses) != 1 {
+		t.Fatal("unexpected container statuses:", e.ContainerStatuses)
+	}
+	cs := e.ContainerStatuses[0]
+	if cs.RestartCount != 3 || cs.State != "Waiting" || cs.Reason != "CrashLoopBackOff" || cs.Init {
+		t.Errorf("unexpected container status: %#v", cs)
+	}
+	if port, ok := e.Port(""); !ok || port.Port != 8086 {
+		t.Error("unexpected pod ports:", e.Ports)
+	}
+}
+
+func TestPodOwner_ReplicaSetNotCached(t *testing.T) {
+	// a ReplicaSet orphaned by its Deployment
+	orphan := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "ReplicaSet",
+		"metadata": map[string]interface{}{
+			"namespace": "appns",
+			"name":      "orphan-5c4f",
+			"uid":       "orphan-uid",
+		},
+	}}
+	coord := newCoord(client.NewFromDynamicClient("", fake.NewSimpleDynamicClient(runtime.NewScheme(), orphan)))
+
+	kind, name := coord.getPodOwner(generateTestReplicaSetPod("orphan-5c4f"))
+	if kind != "ReplicaSet" || name != "orphan-5c4f" {
+		t.Error("unexpected owner:", kind, name)
+	}
+
+	// the name is used when the ReplicaSet cannot be read
+	kind, name = coord.getPodOwner(generateTestReplicaSetPod("app-name-5c4f"))
+	if kind != "Deployment" || name != "app-name" {
+		t.Error("unexpected owner:", kind, name)
+	}
+	kind, name = coord.getPodOwner(generateTestReplicaSetPod("standalone"))
+	if kind != "ReplicaSet" || name != "standalone" {
+		t.Error("unexpected owner:", kind, name)
+	}
+}
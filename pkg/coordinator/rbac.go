@@ -0,0 +1,156 @@
+package coordinator
+
+import (
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// serviceAccountAnnotation and roleAnnotation record, on a workload,
+// the names of the ServiceAccount and of the Role and RoleBinding
+// created alongside it
+const (
+	serviceAccountAnnotation = "coordinator/service-account"
+	roleAnnotation           = "coordinator/role"
+)
+
+var supportedVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete", "deletecollection", "*"}
+
+func validatePermissions(param api.RunParam) field.ErrorList {
+	var errs field.ErrorList
+	fldPath := field.NewPath("permissions")
+	for i, perm := range param.Permissions {
+		idxPath := fldPath.Index(i)
+		if len(perm.Resources) == 0 {
+			errs = append(errs, field.Required(idxPath.Child("resources"), "at least one resource is required"))
+		}
+		for j, res := range perm.Resources {
+			if res == "" {
+				errs = append(errs, field.Invalid(idxPath.Child("resources").Index(j), res, "must not be empty"))
+			}
+		}
+		for j, name := range perm.ResourceNames {
+			if name == "" {
+				errs = append(errs, field.Invalid(idxPath.Child("resourceNames").Index(j), name, "must not be empty"))
+			}
+		}
+		if len(perm.Verbs) == 0 {
+			errs = append(errs, field.Required(idxPath.Child("verbs"), "at least one verb is required"))
+		}
+		for j, verb := range perm.Verbs {
+			if !containsString(supportedVerbs, verb) {
+				errs = append(errs, field.NotSupported(idxPath.Child("verbs").Index(j), verb, supportedVerbs))
+			}
+		}
+	}
+	return errs
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// syncServiceAccount creates or updates the ServiceAccount, Role and
+// RoleBinding requested by param. The applied objects are returned.
+func (c *appCoordinator) syncServiceAccount(param api.RunParam, dryRun bool) ([]*unstructured.Unstructured, error) {
+	if !param.ServiceAccount {
+		return nil, nil
+	}
+
+	sa, _, err := c.apply(api.ServiceAccountsResource, c.generateServiceAccount(param), dryRun)
+	if err != nil {
+		return nil, err
+	}
+	objs := []*unstructured.Unstructured{sa}
+	if len(param.Permissions) == 0 {
+		return objs, nil
+	}
+
+	role, _, err := c.apply(api.RolesResource, c.generateRole(param), dryRun)
+	if err != nil {
+		return nil, err
+	}
+	binding, _, err := c.apply(api.RoleBindingsResource, c.generateRoleBinding(param), dryRun)
+	if err != nil {
+		return nil, err
+	}
+	return append(objs, role, binding), nil
+}
+
+// generateServiceAccountObjects returns the ServiceAccount, Role
+// and RoleBinding requested by param, if any
+func (c *appCoordinator) generateServiceAccountObjects(param api.RunParam) []*unstructured.Unstructured {
+	if !param.ServiceAccount {
+		return nil
+	}
+	objs := []*unstructured.Unstructured{c.generateServiceAccount(param)}
+	if len(param.Permissions) > 0 {
+		objs = append(objs, c.generateRole(param), c.generateRoleBinding(param))
+	}
+	return objs
+}
+
+func (c *appCoordinator) generateServiceAccount(param api.RunParam) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ServiceAccount",
+			"metadata":   c.generateCompanionMeta(param),
+		},
+	}
+}
+
+func (c *appCoordinator) generateRole(param api.RunParam) *unstructured.Unstructured {
+	var rules []interface{}
+	for _, perm := range param.Permissions {
+		apiGroups := perm.APIGroups
+		if len(apiGroups) == 0 {
+			apiGroups = []string{""}
+		}
+		rule := map[string]interface{}{
+			"apiGroups": toInterfaceSlice(apiGroups),
+			"resources": toInterfaceSlice(perm.Resources),
+			"verbs":     toInterfaceSlice(perm.Verbs),
+		}
+		if len(perm.ResourceNames) > 0 {
+			rule["resourceNames"] = toInterfaceSlice(perm.ResourceNames)
+		}
+		rules = append(rules, rule)
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "Role",
+			"metadata":   c.generateCompanionMeta(param),
+			"rules":      rules,
+		},
+	}
+}
+
+func (c *appCoordinator) generateRoleBinding(param api.RunParam) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "RoleBinding",
+			"metadata":   c.generateCompanionMeta(param),
+			"roleRef": map[string]interface{}{
+				"apiGroup": "rbac.authorization.k8s.io",
+				"kind":     "Role",
+				"name":     param.Name,
+			},
+			"subjects": []interface{}{
+				map[string]interface{}{
+					"kind":      "ServiceAccount",
+					"name":      param.Name,
+					"namespace": param.Namespace,
+				},
+			},
+		},
+	}
+}
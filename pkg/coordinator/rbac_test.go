@@ -0,0 +1,165 @@
+package coordinator
+
+import (
+	"testing"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestServiceAccountRun(t *testing.T) {
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	param := api.RunParam{
+		Namespace: "appns",
+		Name:      "worker",
+		Image:     "image:latest",
+		Permissions: []api.Permission{
+			{Resources: []string{"configmaps"}, ResourceNames: []string{"worker-config"}, Verbs: []string{"get", "watch"}},
+			{APIGroups: []string{"batch"}, Resources: []string{"jobs"}, Verbs: []string{"list"}},
+		},
+	}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(res schema.GroupVersionResource) (*unstructured.Unstructured, error) {
+		return fakeClient.Resource(res).Namespace("appns").Get("worker", metav1.GetOptions{})
+	}
+
+	deploy, err := get(api.DeploymentsResource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sa, _, _ := unstructured.NestedString(deploy.Object, "spec", "template", "spec", "serviceAccountName"); sa != "worker" {
+		t.Error("unexpected service account name:", sa)
+	}
+	if _, err := get(api.ServiceAccountsResource); err != nil {
+		t.Fatal(err)
+	}
+
+	role, err := get(api.RolesResource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, _, _ := unstructured.NestedSlice(role.Object, "rules")
+	if len(rules) != 2 {
+		t.Fatal("unexpected rules:", rules)
+	}
+	groups, _, _ := unstructured.NestedStringSlice(rules[0].(map[string]interface{}), "apiGroups")
+	if len(groups) != 1 || groups[0] != "" {
+		t.Error("unexpected default api groups:", groups)
+	}
+	names, _, _ := unstructured.NestedStringSlice(rules[0].(map[string]interface{}), "resourceNames")
+	if len(names) != 1 || names[0] != "worker-config" {
+		t.Error("unexpected resource names:", names)
+	}
+	if _, ok := rules[1].(map[string]interface{})["resourceNames"]; ok {
+		t.Error("unexpected resource names on unrestricted rule")
+	}
+
+	binding, err := get(api.RoleBindingsResource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roleName, _, _ := unstructured.NestedString(binding.Object, "roleRef", "name")
+	subjects, _, _ := unstructured.NestedSlice(binding.Object, "subjects")
+	if roleName != "worker" || len(subjects) != 1 {
+		t.Error("unexpected role binding:", roleName, subjects)
+	}
+
+	// dropping permissions keeps the account only
+	param.Permissions = nil
+	param.ServiceAccount = true
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := get(api.RolesResource); !apierrors.IsNotFound(err) {
+		t.Error("expecting role to be removed, got:", err)
+	}
+	if _, err := get(api.RoleBindingsResource); !apierrors.IsNotFound(err) {
+		t.Error("expecting role binding to be removed, got:", err)
+	}
+	if _, err := get(api.ServiceAccountsResource); err != nil {
+		t.Error(err)
+	}
+
+	// dropping the account removes it once the workload no longer uses it
+	param.ServiceAccount = false
+	fakeClient.ClearActions()
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	var updated bool
+	for _, action := range fakeClient.Actions() {
+		switch {
+		case action.GetResource() == api.DeploymentsResource && action.GetVerb() == "update":
+			updated = true
+		case action.GetResource() == api.ServiceAccountsResource && action.GetVerb() == "delete":
+			if !updated {
+				t.Error("service account deleted before the workload update")
+			}
+		}
+	}
+	if _, err := get(api.ServiceAccountsResource); !apierrors.IsNotFound(err) {
+		t.Error("expecting service account to be removed, got:", err)
+	}
+
+	// deleting the workload removes its account
+	param.ServiceAccount = true
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	if err := coord.Delete(api.DeleteParam{Namespace: "appns", Name: "worker"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := get(api.ServiceAccountsResource); !apierrors.IsNotFound(err) {
+		t.Error("expecting service account to be removed, got:", err)
+	}
+}
+
+func TestServiceAccountNotRequested(t *testing.T) {
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	param := api.RunParam{Namespace: "appns", Name: "worker", Image: "image:latest"}
+	for i := 0; i < 2; i++ {
+		if err := coord.Run(param); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := coord.Delete(api.DeleteParam{Namespace: "appns", Name: "worker"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range fakeClient.Actions() {
+		switch action.GetResource() {
+		case api.ServiceAccountsResource, api.RolesResource, api.RoleBindingsResource:
+			t.Error("unexpected action:", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
+
+func TestValidatePermissions(t *testing.T) {
+	param := api.RunParam{
+		Permissions: []api.Permission{
+			{Resources: []string{"pods"}, Verbs: []string{"get"}},
+			{Verbs: []string{"read"}},
+		},
+	}
+	errs := validatePermissions(param)
+	if len(errs) != 2 {
+		t.Fatal("unexpected errors:", errs)
+	}
+	if errs[0].Field != "permissions[1].resources" || errs[1].Field != "permissions[1].verbs[0]" {
+		t.Error("unexpected error fields:", errs[0].Field, errs[1].Field)
+	}
+}
@@ -0,0 +1,23 @@
+package coordinator
+
+import (
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Render returns the objects Run would apply for param
+// without contacting the cluster
+func (c *appCoordinator) Render(param api.RunParam) (*api.Manifest, error) {
+	if err := assertValidRunParam(param); err != nil {
+		return nil, err
+	}
+	param = c.withRunDefaults(param)
+
+	_, workload := c.generateWorkload(param)
+	manifest := &api.Manifest{Objects: []*unstructured.Unstructured{workload}}
+	if param.Service != api.ServiceNone {
+		manifest.Objects = append(manifest.Objects, c.generateService(param))
+	}
+	manifest.Objects = append(manifest.Objects, c.generateServiceAccountObjects(param)...)
+	return manifest, nil
+}
@@ -0,0 +1,126 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRender(t *testing.T) {
+	coord := &appCoordinator{name: "test-coord"}
+	manifest, err := coord.Render(api.RunParam{
+		Namespace: "appns",
+		Name:      "app",
+		Image:     "image:latest",
+		Port:      8080,
+		Service:   api.ServiceClusterIP,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Objects) != 2 {
+		t.Fatal("unexpected number of objects:", len(manifest.Objects))
+	}
+	if manifest.Objects[0].GetKind() != "Deployment" || manifest.Objects[1].GetKind() != "Service" {
+		t.Error("unexpected objects:", manifest.Objects[0].GetKind(), manifest.Objects[1].GetKind())
+	}
+	if replicas, _, _ := unstructured.NestedInt64(manifest.Objects[0].Object, "spec", "replicas"); replicas != 1 {
+		t.Error("expecting defaulted replicas, got:", replicas)
+	}
+
+	yamlDoc, err := manifest.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := strings.Split(string(yamlDoc), "---\n")
+	if len(docs) != 2 || !strings.Contains(docs[0], "kind: Deployment") || !strings.Contains(docs[1], "kind: Service") {
+		t.Error("unexpected yaml:", string(yamlDoc))
+	}
+
+	jsonDoc, err := manifest.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		Kind  string
+		Items []map[string]interface{}
+	}
+	if err := json.Unmarshal(jsonDoc, &list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Kind != "List" || len(list.Items) != 2 {
+		t.Error("unexpected json:", string(jsonDoc))
+	}
+
+	if _, err := coord.Render(api.RunParam{Name: "app"}); !api.IsValidationError(err) {
+		t.Error("expecting validation error, got:", err)
+	}
+}
+
+func TestRender_DefaultNamespace(t *testing.T) {
+	coord := newCoord(client.NewFromDynamicClient("workers", fake.NewSimpleDynamicClient(runtime.NewScheme())))
+	coord.name = "test-coord"
+	manifest, err := coord.Render(api.RunParam{
+		Name:           "app",
+		Image:          "image:latest",
+		Port:           8080,
+		Service:        api.ServiceClusterIP,
+		ServiceAccount: true,
+		Permissions:    []api.Permission{{Resources: []string{"pods"}, Verbs: []string{"get"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range manifest.Objects {
+		if obj.GetNamespace() != "workers" {
+			t.Errorf("unexpected namespace of %s: %q", obj.GetKind(), obj.GetNamespace())
+		}
+	}
+	if dns := manifest.Objects[0].GetAnnotations()[serviceAnnotation]; dns != "app.workers.svc" {
+		t.Error("unexpected service DNS name:", dns)
+	}
+	binding := manifest.Objects[len(manifest.Objects)-1]
+	subjects, _, _ := unstructured.NestedSlice(binding.Object, "subjects")
+	if len(subjects) != 1 || subjects[0].(map[string]interface{})["namespace"] != "workers" {
+		t.Error("unexpected role binding subjects:", subjects)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	// emulate the server defaulting a dry-run create without persisting it
+	fakeClient.PrependReactor("create", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured).DeepCopy()
+		unstructured.SetNestedField(obj.Object, int64(600), "spec", "progressDeadlineSeconds")
+		return true, obj, nil
+	})
+
+	manifest, err := coord.DryRun(api.RunParam{Namespace: "appns", Name: "app", Image: "image:latest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Objects) != 1 {
+		t.Fatal("unexpected number of objects:", len(manifest.Objects))
+	}
+	deadline, _, _ := unstructured.NestedInt64(manifest.Objects[0].Object, "spec", "progressDeadlineSeconds")
+	if deadline != 600 {
+		t.Error("expecting defaulted result, got deadline:", deadline)
+	}
+
+	_, err = fakeClient.Resource(api.DeploymentsResource).Namespace("appns").Get("app", metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Error("dry run should not persist the deployment:", err)
+	}
+}
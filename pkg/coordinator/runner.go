@@ -2,90 +2,748 @@ package coordinator
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/vladimirvivien/horizon/pkg/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
 )
 
 func (c *appCoordinator) Run(param api.RunParam) error {
+	_, err := c.run(param, false)
+	return err
+}
+
+// DryRun submits the objects Run would apply with dryRun=All and
+// returns them as defaulted by the API server. Nothing is persisted,
+// stale companion objects are not removed, and a service changing
+// between ClusterIP and Headless is returned as generated.
+func (c *appCoordinator) DryRun(param api.RunParam) (*api.Manifest, error) {
+	return c.run(param, true)
+}
+
+func (c *appCoordinator) run(param api.RunParam, dryRun bool) (*api.Manifest, error) {
 	if err := assertValidRunParam(param); err != nil {
-		return err
+		return nil, err
+	}
+	param = c.withRunDefaults(param)
+	if err := c.assertVolumeSourcesExist(param); err != nil {
+		return nil, err
+	}
+
+	// the service account must exist before pods are created
+	accountObjs, err := c.syncServiceAccount(param, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	// create or update object
+	res, workload := c.generateWorkload(param)
+	if !dryRun {
+		if err := c.watch(res); err != nil {
+			return nil, err
+		}
+	}
+	applied, previous, err := c.apply(res, workload, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &api.Manifest{Objects: []*unstructured.Unstructured{applied}}
+
+	svc, err := c.syncService(param, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if svc != nil {
+		manifest.Objects = append(manifest.Objects, svc)
+	}
+	manifest.Objects = append(manifest.Objects, accountObjs...)
+
+	// the pods stop using the removed objects once the workload is updated
+	if !dryRun {
+		if err := c.deleteStaleCompanions(param, previous, workload); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// withRunDefaults returns param with the defaults applied by Run,
+// the namespace being set before any name is generated from it
+func (c *appCoordinator) withRunDefaults(param api.RunParam) api.RunParam {
+	if param.Namespace == "" {
+		param.Namespace = c.k8sClient.Namespace()
+	}
+	if param.Kind == "" {
+		param.Kind = api.WorkloadDeployment
 	}
 	if param.Replicas == 0 {
 		param.Replicas = 1
 	}
+	// StatefulSets are governed by a headless service
+	if param.Kind == api.WorkloadStatefulSet && param.Service == api.ServiceNone {
+		param.Service = api.ServiceHeadless
+	}
+	if len(param.Permissions) > 0 {
+		param.ServiceAccount = true
+	}
+	param.SecurityContext = withSecurityDefaults(param.SecurityContext)
+	return param
+}
 
-	// create object
-	cl := c.k8sClient.Interface()
-	deployment := c.generateDeployment(param)
-	_, err := cl.Resource(api.DeploymentsResource).Namespace(param.Namespace).Create(deployment, metav1.CreateOptions{})
-	if err != nil {
+// generateWorkload returns the object of the kind requested by param
+// along with the resource used to manage it
+func (c *appCoordinator) generateWorkload(param api.RunParam) (schema.GroupVersionResource, *unstructured.Unstructured) {
+	switch param.Kind {
+	case api.WorkloadStatefulSet:
+		return api.StatefulSetsResource, c.generateStatefulSet(param)
+	case api.WorkloadDaemonSet:
+		return api.DaemonSetsResource, c.generateDaemonSet(param)
+	case api.WorkloadJob:
+		return api.JobsResource, c.generateJob(param)
+	case api.WorkloadCronJob:
+		return api.CronJobsResource, c.generateCronJob(param)
+	default:
+		return api.DeploymentsResource, c.generateDeployment(param)
+	}
+}
+
+// workloadResource returns the resource used to manage workloads of kind
+func workloadResource(kind api.WorkloadKind) (schema.GroupVersionResource, error) {
+	switch kind {
+	case "", api.WorkloadDeployment:
+		return api.DeploymentsResource, nil
+	case api.WorkloadStatefulSet:
+		return api.StatefulSetsResource, nil
+	case api.WorkloadDaemonSet:
+		return api.DaemonSetsResource, nil
+	case api.WorkloadJob:
+		return api.JobsResource, nil
+	case api.WorkloadCronJob:
+		return api.CronJobsResource, nil
+	}
+	return schema.GroupVersionResource{}, fmt.Errorf("invalid workload kind %q", kind)
+}
+
+// apply creates obj or, if it already exists and is managed by this
+// coordinator, updates it to the labels and spec of obj. It returns
+// the resulting object, and the object it replaced or nil if obj was
+// created. With dryRun, the server does not persist the change.
+// Conflicting writes are retried.
+func (c *appCoordinator) apply(res schema.GroupVersionResource, obj *unstructured.Unstructured, dryRun bool) (*unstructured.Unstructured, *unstructured.Unstructured, error) {
+	var dryRunOpt []string
+	if dryRun {
+		dryRunOpt = []string{metav1.DryRunAll}
+	}
+
+	var result, previous *unstructured.Unstructured
+	cl := c.k8sClient.Interface().Resource(res).Namespace(obj.GetNamespace())
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := cl.Get(obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			previous = nil
+			result, err = cl.Create(obj, metav1.CreateOptions{DryRun: dryRunOpt})
+			if apierrors.IsAlreadyExists(err) {
+				// created concurrently, retry as an update
+				return apierrors.NewConflict(res.GroupResource(), obj.GetName(), err)
+			}
+			return err
+		}
+		previous = existing
+
+		if !c.isManaged(existing) {
+			return &api.ConflictError{
+				Kind:        obj.GetKind(),
+				Namespace:   obj.GetNamespace(),
+				Name:        obj.GetName(),
+				Coordinator: c.name,
+			}
+		}
+
+		// the spec of a Job cannot be updated, a Job run
+		// again with the same spec is left as is
+		if res == api.JobsResource {
+			if err := assertJobUnchanged(existing, obj); err != nil {
+				return err
+			}
+			result = existing
+			return nil
+		}
+
+		if recreate := recreatedOnChange[res]; recreate != nil && recreate(existing, obj) {
+			if dryRun {
+				result = obj
+				return nil
+			}
+			// deleted and retried as a creation
+			uid := existing.GetUID()
+			err := cl.Delete(obj.GetName(), &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			return apierrors.NewConflict(res.GroupResource(), obj.GetName(), fmt.Errorf("%s recreated", obj.GetKind()))
+		}
+
+		updated := generateApplyObject(res, existing, obj, c.ownerUID())
+		result, err = cl.Update(updated, metav1.UpdateOptions{DryRun: dryRunOpt})
 		return err
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	return nil
+	return result, previous, nil
 }
 
-func assertValidRunParam(param api.RunParam) error {
-	if param.Name == "" {
-		return errors.New("missing deployment name")
+// isManaged returns true if obj carries the labels stamped by this coordinator
+func (c *appCoordinator) isManaged(obj *unstructured.Unstructured) bool {
+	lbls := obj.GetLabels()
+	return lbls["coordinated"] == "true" && lbls["coordinator"] == c.name
+}
+
+// labelsAnnotation records the keys of the labels set by the
+// coordinator, so that those dropped from a later Run are removed
+// without touching the labels set by others
+const labelsAnnotation = "coordinator/labels"
+
+// recreatedOnChange lists, per resource, the changes that cannot be
+// made by an update. Apply deletes and recreates such objects. With
+// dryRun, the generated object is returned instead.
+var recreatedOnChange = map[schema.GroupVersionResource]func(existing, desired *unstructured.Unstructured) bool{
+	api.ServicesResource: isServiceTypeChanged,
+}
+
+// allocatedFields lists, per resource, the fields allocated by the
+// API server that apply carries over when desired leaves them unset
+var allocatedFields = map[schema.GroupVersionResource][][]string{
+	api.ServicesResource: {{"spec", "clusterIP"}, {"spec", "clusterIPs"}},
+}
+
+// generateApplyObject returns existing moved to the spec and labels of
+// desired. The spec, and the other top-level fields of desired such as
+// the rules of a role, replace those of existing so that fields dropped
+// from desired are removed. Labels and annotations set by others, and
+// owner references other than the one to ownerUID, are preserved.
+// The resourceVersion of existing is kept so that concurrent
+// modifications are rejected with a conflict.
+func generateApplyObject(res schema.GroupVersionResource, existing, desired *unstructured.Unstructured, ownerUID types.UID) *unstructured.Unstructured {
+	result := existing.DeepCopy()
+	recorded := strings.Split(existing.GetAnnotations()[labelsAnnotation], ",")
+	result.SetLabels(reconcileLabels(existing.GetLabels(), desired.GetLabels(), recorded))
+
+	// annotations are shared with other controllers, only
+	// those set by the coordinator are removed
+	annotations := existing.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	for k := range annotations {
+		if strings.HasPrefix(k, "coordinator/") {
+			delete(annotations, k)
+		}
+	}
+	for k, v := range desired.GetAnnotations() {
+		annotations[k] = v
+	}
+	result.SetAnnotations(annotations)
+
+	var ownerRefs []metav1.OwnerReference
+	for _, ref := range existing.GetOwnerReferences() {
+		if ref.UID != ownerUID {
+			ownerRefs = append(ownerRefs, ref)
+		}
+	}
+	ownerRefs = append(ownerRefs, desired.GetOwnerReferences()...)
+	result.SetOwnerReferences(ownerRefs)
+
+	// objects without a spec, such as roles, carry
+	// their content in top-level fields
+	for k, v := range desired.Object {
+		switch k {
+		case "apiVersion", "kind", "metadata", "status":
+			continue
+		}
+		result.Object[k] = runtime.DeepCopyJSONValue(v)
+	}
+
+	// the pod template keeps the annotations set by others,
+	// such as the restart stamp of kubectl rollout restart
+	if tmplLabels, ok, _ := unstructured.NestedStringMap(desired.Object, "spec", "template", "metadata", "labels"); ok {
+		tmplMeta, _, _ := unstructured.NestedMap(existing.Object, "spec", "template", "metadata")
+		if tmplMeta == nil {
+			tmplMeta = make(map[string]interface{})
+		}
+		oldTmplLabels, _, _ := unstructured.NestedStringMap(tmplMeta, "labels")
+		unstructured.SetNestedStringMap(tmplMeta, reconcileLabels(oldTmplLabels, tmplLabels, recorded), "labels")
+		unstructured.SetNestedMap(result.Object, tmplMeta, "spec", "template", "metadata")
+	}
+
+	for _, fields := range allocatedFields[res] {
+		if _, ok, _ := unstructured.NestedFieldNoCopy(desired.Object, fields...); ok {
+			continue
+		}
+		if val, ok, _ := unstructured.NestedFieldCopy(existing.Object, fields...); ok {
+			unstructured.SetNestedField(result.Object, val, fields...)
+		}
+	}
+	return result
+}
+
+// reconcileLabels returns the existing labels updated to the desired
+// ones. Of the other labels, only those recorded as set by the
+// coordinator are removed.
+func reconcileLabels(existing, desired map[string]string, recorded []string) map[string]string {
+	result := make(map[string]string)
+	for k, v := range existing {
+		result[k] = v
+	}
+	for _, k := range recorded {
+		delete(result, k)
+	}
+	for k, v := range desired {
+		result[k] = v
+	}
+	return result
+}
+
+// generateLabelsAnnotation returns the value of labelsAnnotation
+// for the given labels
+func generateLabelsAnnotation(lbls map[string]interface{}) string {
+	keys := make([]string, 0, len(lbls))
+	for k := range lbls {
+		keys = append(keys, k)
 	}
-	if param.Image == "" {
-		return errors.New("missing deployment image")
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// assertVolumeSourcesExist verifies that the ConfigMaps and
+// Secrets referenced by the param volumes can be found
+func (c *appCoordinator) assertVolumeSourcesExist(param api.RunParam) error {
+	cl := c.k8sClient.Interface()
+	for _, vol := range param.Volumes {
+		var res schema.GroupVersionResource
+		var name string
+		switch {
+		case vol.ConfigMap != "":
+			res, name = api.ConfigMapsResource, vol.ConfigMap
+		case vol.Secret != "":
+			res, name = api.SecretsResource, vol.Secret
+		default:
+			continue
+		}
+		if _, err := cl.Resource(res).Namespace(param.Namespace).Get(name, metav1.GetOptions{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Errorf("volume %s: %s %s/%s not found", vol.Name, res.Resource, param.Namespace, name)
+			}
+			return err
+		}
 	}
 	return nil
 }
 
+// parseEnvs converts KEY=VALUE entries into container env vars.
+// The value may be empty or contain additional '=' characters.
+func parseEnvs(envs []string) ([]interface{}, error) {
+	var result []interface{}
+	for _, env := range envs {
+		envVar, err := parseEnv(env)
+		if err != nil {
+			return nil, fmt.Errorf("invalid env %q: %s", env, err)
+		}
+		result = append(result, envVar)
+	}
+	return result, nil
+}
+
+func parseEnv(env string) (map[string]interface{}, error) {
+	parts := strings.SplitN(env, "=", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("expecting KEY=VALUE")
+	}
+	key := strings.TrimSpace(parts[0])
+	if key == "" {
+		return nil, errors.New("missing key")
+	}
+	if msgs := validation.IsEnvVarName(key); len(msgs) > 0 {
+		return nil, errors.New(strings.Join(msgs, ", "))
+	}
+	return map[string]interface{}{
+		"name":  key,
+		"value": parts[1],
+	}, nil
+}
+
+// parseLabels converts a selector-style string (k=v,k2=v2) into a label set.
+// Keys reserved for the coordinator labels are rejected.
+func parseLabels(selector string) (labels.Set, error) {
+	set, err := labels.ConvertSelectorToLabelsMap(selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"app", "coordinated", "coordinator"} {
+		if set.Has(key) {
+			return nil, fmt.Errorf("label %q is reserved by the coordinator", key)
+		}
+	}
+	return set, nil
+}
+
+// parseResources converts requests and limits into a container resources
+// map. Each request must not exceed the limit of the same resource.
+func parseResources(requests, limits api.Resources) (map[string]interface{}, error) {
+	reqs, err := parseResourceList("requests", requests)
+	if err != nil {
+		return nil, err
+	}
+	lims, err := parseResourceList("limits", limits)
+	if err != nil {
+		return nil, err
+	}
+
+	if name, ok := exceededLimit(reqs, lims); ok {
+		req, lim := reqs[name], lims[name]
+		return nil, fmt.Errorf("invalid %s request %s: exceeds limit %s", name, req.String(), lim.String())
+	}
+
+	result := make(map[string]interface{})
+	if len(reqs) > 0 {
+		result["requests"] = quantitiesToMap(reqs)
+	}
+	if len(lims) > 0 {
+		result["limits"] = quantitiesToMap(lims)
+	}
+	return result, nil
+}
+
+func quantitiesToMap(quantities map[string]resource.Quantity) map[string]interface{} {
+	result := make(map[string]interface{})
+	for name, q := range quantities {
+		result[name] = q.String()
+	}
+	return result
+}
+
+func parseResourceList(kind string, res api.Resources) (map[string]resource.Quantity, error) {
+	result := make(map[string]resource.Quantity)
+	for _, entry := range resourceValues(res) {
+		if entry.value == "" {
+			continue
+		}
+		q, err := parseQuantity(entry.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s %q: %s", entry.name, kind, entry.value, err)
+		}
+		result[entry.name] = q
+	}
+	return result, nil
+}
+
+type resourceValue struct {
+	name, value string
+}
+
+// resourceValues returns the quantities of res by resource name
+func resourceValues(res api.Resources) []resourceValue {
+	return []resourceValue{
+		{"cpu", res.CPU},
+		{"memory", res.Memory},
+		{"ephemeral-storage", res.EphemeralStorage},
+	}
+}
+
+func parseQuantity(val string) (resource.Quantity, error) {
+	q, err := resource.ParseQuantity(val)
+	if err != nil {
+		return q, err
+	}
+	if q.Sign() < 0 {
+		return q, errors.New("must not be negative")
+	}
+	return q, nil
+}
+
+// exceededLimit returns the name of the first resource, in the
+// order of resourceValues, whose request exceeds its limit
+func exceededLimit(reqs, lims map[string]resource.Quantity) (string, bool) {
+	for _, entry := range resourceValues(api.Resources{}) {
+		req, ok := reqs[entry.name]
+		if !ok {
+			continue
+		}
+		if lim, ok := lims[entry.name]; ok && req.Cmp(lim) > 0 {
+			return entry.name, true
+		}
+	}
+	return "", false
+}
+
+// generateProbe renders probe into a container probe map.
+// HTTP and TCP probes without a port target defaultPort.
+func generateProbe(probe *api.Probe, defaultPort int64) map[string]interface{} {
+	result := make(map[string]interface{})
+	switch {
+	case probe.HTTP != nil:
+		port := probe.HTTP.Port
+		if port == 0 {
+			port = defaultPort
+		}
+		path := probe.HTTP.Path
+		if path == "" {
+			path = "/"
+		}
+		httpGet := map[string]interface{}{
+			"path": path,
+			"port": port,
+		}
+		if probe.HTTP.Scheme != "" {
+			httpGet["scheme"] = probe.HTTP.Scheme
+		}
+		result["httpGet"] = httpGet
+	case probe.TCP != nil:
+		port := probe.TCP.Port
+		if port == 0 {
+			port = defaultPort
+		}
+		result["tcpSocket"] = map[string]interface{}{"port": port}
+	default:
+		result["exec"] = map[string]interface{}{"command": toInterfaceSlice(probe.Exec)}
+	}
+
+	fields := map[string]int64{
+		"initialDelaySeconds": probe.InitialDelaySeconds,
+		"periodSeconds":       probe.PeriodSeconds,
+		"timeoutSeconds":      probe.TimeoutSeconds,
+		"successThreshold":    probe.SuccessThreshold,
+		"failureThreshold":    probe.FailureThreshold,
+	}
+	for field, val := range fields {
+		if val > 0 {
+			result[field] = val
+		}
+	}
+	return result
+}
+
+// generateVolumes renders the pod volumes and the
+// matching container volume mounts
+func generateVolumes(volumes []api.Volume) ([]interface{}, []interface{}) {
+	var vols, mounts []interface{}
+	for _, vol := range volumes {
+		volume := map[string]interface{}{"name": vol.Name}
+		switch {
+		case vol.ConfigMap != "":
+			volume["configMap"] = map[string]interface{}{"name": vol.ConfigMap}
+		case vol.Secret != "":
+			volume["secret"] = map[string]interface{}{"secretName": vol.Secret}
+		case vol.PersistentVolumeClaim != "":
+			volume["persistentVolumeClaim"] = map[string]interface{}{
+				"claimName": vol.PersistentVolumeClaim,
+				"readOnly":  vol.ReadOnly,
+			}
+		case vol.EmptyDir != nil:
+			emptyDir := make(map[string]interface{})
+			if vol.EmptyDir.Medium != "" {
+				emptyDir["medium"] = vol.EmptyDir.Medium
+			}
+			if vol.EmptyDir.SizeLimit != "" {
+				emptyDir["sizeLimit"] = vol.EmptyDir.SizeLimit
+			}
+			volume["emptyDir"] = emptyDir
+		}
+		vols = append(vols, volume)
+
+		mount := map[string]interface{}{
+			"name":      vol.Name,
+			"mountPath": vol.MountPath,
+		}
+		if vol.SubPath != "" {
+			mount["subPath"] = vol.SubPath
+		}
+		if vol.ReadOnly {
+			mount["readOnly"] = true
+		}
+		mounts = append(mounts, mount)
+	}
+	return vols, mounts
+}
+
+func toInterfaceSlice(vals []string) []interface{} {
+	result := make([]interface{}, len(vals))
+	for i, val := range vals {
+		result[i] = val
+	}
+	return result
+}
+
+func (c *appCoordinator) generateLabels(param api.RunParam) map[string]interface{} {
+	result := make(map[string]interface{})
+	// param validated by assertValidRunParam
+	userLabels, _ := parseLabels(param.Labels)
+	for k, v := range userLabels {
+		result[k] = v
+	}
+	for k, v := range c.generateSelector(param) {
+		result[k] = v
+	}
+	return result
+}
+
+func (c *appCoordinator) generateSelector(param api.RunParam) map[string]interface{} {
+	result := make(map[string]interface{})
+	for k, v := range c.selectorLabels(param.Name) {
+		result[k] = v
+	}
+	return result
+}
+
+// selectorLabels returns the labels stamped on, and used to select,
+// the pods of the named workload
+func (c *appCoordinator) selectorLabels(name string) labels.Set {
+	return labels.Set{
+		"app":         name,
+		"coordinated": "true",
+		"coordinator": c.name,
+	}
+}
+
 func (c *appCoordinator) generateDeployment(param api.RunParam) *unstructured.Unstructured {
 	return &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"apiVersion": "apps/v1",
 			"kind":       "Deployment",
-			"metadata": map[string]interface{}{
-				"name":      param.Name,
-				"namespace": param.Namespace,
-				"labels": map[string]interface{}{
-					"app":         param.Name,
-					"coordinated": "true",
-					"coordinator": c.name,
-				},
-			},
+			"metadata":   c.generateObjectMeta(param),
 			"spec": map[string]interface{}{
 				"selector": map[string]interface{}{
-					"matchLabels": map[string]interface{}{
-						"app":         param.Name,
-						"coordinated": "true",
-						"coordinator": c.name,
-					},
+					"matchLabels": c.generateSelector(param),
 				},
 				"replicas": param.Replicas,
-				"template": map[string]interface{}{
-					"metadata": map[string]interface{}{
-						"labels": map[string]interface{}{
-							"app":         param.Name,
-							"coordinated": "true",
-							"coordinator": c.name,
-						},
-					},
-
-					"spec": map[string]interface{}{
-						"containers": []interface{}{
-							map[string]interface{}{
-								"name":            param.Name,
-								"image":           param.Image,
-								"imagePullPolicy": "IfNotPresent",
-								"ports": []interface{}{
-									map[string]interface{}{
-										"name":          "api",
-										"protocol":      "TCP",
-										"containerPort": param.Port,
-									},
-								},
-							},
-						},
-					},
-				},
+				"template": c.generatePodTemplate(param),
 			},
 		},
 	}
 }
+
+// generateObjectMeta returns the metadata of the workload
+func (c *appCoordinator) generateObjectMeta(param api.RunParam) map[string]interface{} {
+	labels := c.generateLabels(param)
+	annotations := map[string]interface{}{
+		labelsAnnotation: generateLabelsAnnotation(labels),
+	}
+	if param.Service != api.ServiceNone {
+		annotations[serviceAnnotation] = serviceDNSName(param.Name, param.Namespace)
+	}
+	if param.ServiceAccount {
+		annotations[serviceAccountAnnotation] = param.Name
+	}
+	if len(param.Permissions) > 0 {
+		annotations[roleAnnotation] = param.Name
+	}
+	meta := map[string]interface{}{
+		"name":        param.Name,
+		"namespace":   param.Namespace,
+		"labels":      labels,
+		"annotations": annotations,
+	}
+	if refs := c.generateOwnerReferences(param); refs != nil {
+		meta["ownerReferences"] = refs
+	}
+	return meta
+}
+
+func (c *appCoordinator) generatePodTemplate(param api.RunParam) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": c.generateLabels(param),
+		},
+		"spec": c.generateScheduling(param, generatePodSpec(param)),
+	}
+}
+
+func generatePodSpec(param api.RunParam) map[string]interface{} {
+	container := generateWorkerContainer(param)
+	containers := []interface{}{container}
+	for _, sidecar := range param.Sidecars {
+		containers = append(containers, generateContainer(sidecar))
+	}
+	podSpec := map[string]interface{}{
+		"containers": containers,
+	}
+	if param.ServiceAccount {
+		podSpec["serviceAccountName"] = param.Name
+	}
+
+	if len(param.InitContainers) > 0 {
+		var initContainers []interface{}
+		for _, init := range param.InitContainers {
+			initContainers = append(initContainers, generateContainer(init))
+		}
+		podSpec["initContainers"] = initContainers
+	}
+
+	volumes, mounts := generateVolumes(param.Volumes)
+	if len(volumes) > 0 {
+		podSpec["volumes"] = volumes
+	}
+	mounts = append(mounts, generateVolumeClaimMounts(param.VolumeClaims)...)
+	if len(mounts) > 0 {
+		container["volumeMounts"] = mounts
+	}
+	return generateSecurityContext(param.SecurityContext, podSpec)
+}
+
+func generateWorkerContainer(param api.RunParam) map[string]interface{} {
+	container := map[string]interface{}{
+		"name":            param.Name,
+		"image":           param.Image,
+		"imagePullPolicy": pullPolicyOrDefault(param.ImagePullPolicy),
+	}
+	if ports := workerPorts(param); len(ports) > 0 {
+		container["ports"] = generatePorts(ports)
+	}
+
+	if len(param.Command) > 0 {
+		container["command"] = toInterfaceSlice(param.Command)
+	}
+	if len(param.Args) > 0 {
+		container["args"] = toInterfaceSlice(param.Args)
+	}
+	if param.WorkingDir != "" {
+		container["workingDir"] = param.WorkingDir
+	}
+
+	// param validated by assertValidRunParam
+	if envs, _ := parseEnvs(param.Envs); len(envs) > 0 {
+		container["env"] = envs
+	}
+	if resources, _ := parseResources(param.Requests, param.Limits); len(resources) > 0 {
+		container["resources"] = resources
+	}
+
+	readiness := param.ReadinessProbe
+	if readiness == nil && param.HTTPReadiness {
+		readiness = &api.Probe{HTTP: &api.HTTPProbe{}}
+	}
+	if param.LivenessProbe != nil {
+		container["livenessProbe"] = generateProbe(param.LivenessProbe, param.Port)
+	}
+	if readiness != nil {
+		container["readinessProbe"] = generateProbe(readiness, param.Port)
+	}
+	if param.StartupProbe != nil {
+		container["startupProbe"] = generateProbe(param.StartupProbe, param.Port)
+	}
+	return container
+}
@@ -2,6 +2,7 @@ package coordinator
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
@@ -46,7 +47,7 @@ func TestRunner(t *testing.T) {
 			}
 
 			// validate creation
-			savedObj, err := fakeClient.Resource(deploymentsResource).Namespace(test.param.Namespace).Get(test.param.Name, metav1.GetOptions{})
+			savedObj, err := fakeClient.Resource(api.DeploymentsResource).Namespace(test.param.Namespace).Get(test.param.Name, metav1.GetOptions{})
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -68,3 +69,578 @@ func TestRunner(t *testing.T) {
 		})
 	}
 }
+
+func TestRunnerGenerateDeployment(t *testing.T) {
+	coord := newCoord(client.NewFromDynamicClient("", fake.NewSimpleDynamicClient(runtime.NewScheme())))
+	coord.name = "test-coord"
+	param := api.RunParam{
+		Namespace:       "appns",
+		Name:            "app-name",
+		Image:           "test.app.image:latest",
+		ImagePullPolicy: "Never",
+		Envs:            []string{"MODE=worker", "OPTS=a=b", "EMPTY="},
+		Labels:          "tier=backend, team=infra",
+		Replicas:        1,
+	}
+	deployment := coord.generateDeployment(param)
+
+	for _, path := range [][]string{{"metadata", "labels"}, {"spec", "template", "metadata", "labels"}} {
+		lbls, ok, err := unstructured.NestedStringMap(deployment.Object, path...)
+		if err != nil || !ok {
+			t.Fatalf("failed to get labels at %v: %v", path, err)
+		}
+		expected := map[string]string{
+			"app":         "app-name",
+			"coordinated": "true",
+			"coordinator": "test-coord",
+			"tier":        "backend",
+			"team":        "infra",
+		}
+		if len(lbls) != len(expected) {
+			t.Errorf("unexpected labels at %v: %v", path, lbls)
+		}
+		for k, v := range expected {
+			if lbls[k] != v {
+				t.Errorf("unexpected label %s=%s at %v", k, lbls[k], path)
+			}
+		}
+	}
+
+	selector, _, _ := unstructured.NestedStringMap(deployment.Object, "spec", "selector", "matchLabels")
+	if _, ok := selector["tier"]; ok {
+		t.Error("user labels should not be part of the selector")
+	}
+
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	if len(containers) != 1 {
+		t.Fatal("unexpected container count:", len(containers))
+	}
+	container := containers[0].(map[string]interface{})
+	if container["imagePullPolicy"] != "Never" {
+		t.Error("unexpected imagePullPolicy:", container["imagePullPolicy"])
+	}
+	envs, _, _ := unstructured.NestedSlice(container, "env")
+	expectedEnvs := [][2]string{{"MODE", "worker"}, {"OPTS", "a=b"}, {"EMPTY", ""}}
+	if len(envs) != len(expectedEnvs) {
+		t.Fatal("unexpected env count:", len(envs))
+	}
+	for i, env := range envs {
+		e := env.(map[string]interface{})
+		if e["name"] != expectedEnvs[i][0] || e["value"] != expectedEnvs[i][1] {
+			t.Errorf("unexpected env %v", e)
+		}
+	}
+
+	// default pull policy
+	param.ImagePullPolicy = ""
+	deployment = coord.generateDeployment(param)
+	containers, _, _ = unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	if policy := containers[0].(map[string]interface{})["imagePullPolicy"]; policy != "IfNotPresent" {
+		t.Error("unexpected default imagePullPolicy:", policy)
+	}
+}
+
+func TestAssertValidRunParam(t *testing.T) {
+	tests := []struct {
+		name       string
+		param      api.RunParam
+		shouldFail bool
+	}{
+		{
+			name:  "valid param",
+			param: api.RunParam{Name: "app", Image: "image:latest", ImagePullPolicy: "Always", Envs: []string{"A=1"}, Labels: "k=v"},
+		},
+		{
+			name:       "missing name",
+			param:      api.RunParam{Image: "image:latest"},
+			shouldFail: true,
+		},
+		{
+			name:       "missing image",
+			param:      api.RunParam{Name: "app"},
+			shouldFail: true,
+		},
+		{
+			name:       "bad pull policy",
+			param:      api.RunParam{Name: "app", Image: "image:latest", ImagePullPolicy: "Sometimes"},
+			shouldFail: true,
+		},
+		{
+			name:       "env missing value separator",
+			param:      api.RunParam{Name: "app", Image: "image:latest", Envs: []string{"A"}},
+			shouldFail: true,
+		},
+		{
+			name:       "env missing key",
+			param:      api.RunParam{Name: "app", Image: "image:latest", Envs: []string{"=1"}},
+			shouldFail: true,
+		},
+		{
+			name:       "malformed labels",
+			param:      api.RunParam{Name: "app", Image: "image:latest", Labels: "k=v,bad"},
+			shouldFail: true,
+		},
+		{
+			name:       "reserved label",
+			param:      api.RunParam{Name: "app", Image: "image:latest", Labels: "coordinator=other"},
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := assertValidRunParam(test.param)
+			if test.shouldFail && err == nil {
+				t.Error("expecting validation error")
+			}
+			if !test.shouldFail && err != nil {
+				t.Error("unexpected error:", err)
+			}
+		})
+	}
+}
+
+func TestRunnerApply(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   *unstructured.Unstructured
+		param      api.RunParam
+		shouldFail bool
+	}{
+		{
+			name: "update managed deployment",
+			existing: func() *unstructured.Unstructured {
+				c := &appCoordinator{name: "test-coord"}
+				return c.generateDeployment(api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:v1", Labels: "stale=true", Replicas: 1})
+			}(),
+			param: api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:v2", Labels: "fresh=true", Replicas: 3},
+		},
+		{
+			name: "refuse unmanaged deployment",
+			existing: func() *unstructured.Unstructured {
+				c := &appCoordinator{name: "other-coord"}
+				return c.generateDeployment(api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:v1", Replicas: 1})
+			}(),
+			param:      api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:v2", Replicas: 3},
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timeout := time.Duration(3 * time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme(), test.existing)
+			coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+			coord.name = "test-coord"
+			if err := coord.Start(ctx.Done()); err != nil {
+				t.Fatal(err)
+			}
+
+			err := coord.Run(test.param)
+			if test.shouldFail {
+				if !api.IsConflictError(err) {
+					t.Fatal("expecting conflict error, got:", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			savedObj, err := fakeClient.Resource(api.DeploymentsResource).Namespace(test.param.Namespace).Get(test.param.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			replicas, _, _ := unstructured.NestedInt64(savedObj.Object, "spec", "replicas")
+			if replicas != test.param.Replicas {
+				t.Error("unexpected replica count:", replicas)
+			}
+			containers, _, _ := unstructured.NestedSlice(savedObj.Object, "spec", "template", "spec", "containers")
+			if image := containers[0].(map[string]interface{})["image"]; image != test.param.Image {
+				t.Error("unexpected image:", image)
+			}
+			lbls := savedObj.GetLabels()
+			if _, ok := lbls["stale"]; ok {
+				t.Error("stale label not removed")
+			}
+			if lbls["fresh"] != "true" || lbls["coordinator"] != "test-coord" {
+				t.Error("unexpected labels:", lbls)
+			}
+		})
+	}
+}
+
+func TestRunnerApply_RemovedFields(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+
+	param := api.RunParam{
+		Namespace:    "appns",
+		Name:         "app-name",
+		Image:        "image:v1",
+		Labels:       "tier=web",
+		NodeSelector: map[string]string{"disk": "ssd"},
+		Requests:     api.Resources{CPU: "100m"},
+	}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+
+	// labels and annotations set by others
+	cl := fakeClient.Resource(api.DeploymentsResource).Namespace("appns")
+	deployment, err := cl.Get("app-name", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lbls := deployment.GetLabels()
+	lbls["team"] = "payments"
+	deployment.SetLabels(lbls)
+	unstructured.SetNestedField(deployment.Object, "payments", "spec", "template", "metadata", "labels", "team")
+	unstructured.SetNestedField(deployment.Object, "now", "spec", "template", "metadata", "annotations", "kubectl.kubernetes.io/restartedAt")
+	if _, err := cl.Update(deployment, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	param.Labels = ""
+	param.NodeSelector = nil
+	param.Requests = api.Resources{}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment, err = cl.Get("app-name", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := unstructured.NestedFieldNoCopy(deployment.Object, "spec", "template", "spec", "nodeSelector"); ok {
+		t.Error("node selector not removed")
+	}
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	if _, ok := containers[0].(map[string]interface{})["resources"]; ok {
+		t.Error("resources not removed")
+	}
+	for _, lbls := range []map[string]string{
+		deployment.GetLabels(),
+		func() map[string]string {
+			lbls, _, _ := unstructured.NestedStringMap(deployment.Object, "spec", "template", "metadata", "labels")
+			return lbls
+		}(),
+	} {
+		if _, ok := lbls["tier"]; ok {
+			t.Error("dropped label not removed:", lbls)
+		}
+		if lbls["team"] != "payments" || lbls["app"] != "app-name" {
+			t.Error("unexpected labels:", lbls)
+		}
+	}
+	if restarted, _, _ := unstructured.NestedString(deployment.Object, "spec", "template", "metadata", "annotations", "kubectl.kubernetes.io/restartedAt"); restarted != "now" {
+		t.Error("pod template annotation not preserved")
+	}
+}
+
+func TestRunnerGenerateResources(t *testing.T) {
+	tests := []struct {
+		name       string
+		requests   api.Resources
+		limits     api.Resources
+		expected   map[string]map[string]string
+		shouldFail bool
+	}{
+		{
+			name: "no resources",
+		},
+		{
+			name:     "requests and limits",
+			requests: api.Resources{CPU: "250m", Memory: "64Mi"},
+			limits:   api.Resources{CPU: "1", Memory: "128Mi", EphemeralStorage: "1Gi"},
+			expected: map[string]map[string]string{
+				"requests": {"cpu": "250m", "memory": "64Mi"},
+				"limits":   {"cpu": "1", "memory": "128Mi", "ephemeral-storage": "1Gi"},
+			},
+		},
+		{
+			name:       "malformed quantity",
+			requests:   api.Resources{CPU: "lots"},
+			shouldFail: true,
+		},
+		{
+			name:       "request exceeds limit",
+			requests:   api.Resources{Memory: "1Gi"},
+			limits:     api.Resources{Memory: "512Mi"},
+			shouldFail: true,
+		},
+	}
+
+	coord := &appCoordinator{name: "test-coord"}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			param := api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:latest", Requests: test.requests, Limits: test.limits}
+			err := assertValidRunParam(param)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expecting validation error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			deployment := coord.generateDeployment(param)
+			containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+			resources, ok, _ := unstructured.NestedMap(containers[0].(map[string]interface{}), "resources")
+			if len(test.expected) == 0 {
+				if ok {
+					t.Error("unexpected resources:", resources)
+				}
+				return
+			}
+			for kind, quantities := range test.expected {
+				rendered, _, _ := unstructured.NestedStringMap(resources, kind)
+				if len(rendered) != len(quantities) {
+					t.Errorf("unexpected %s: %v", kind, rendered)
+				}
+				for name, q := range quantities {
+					if rendered[name] != q {
+						t.Errorf("unexpected %s %s: %s", kind, name, rendered[name])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerGenerateProbes(t *testing.T) {
+	tests := []struct {
+		name       string
+		param      api.RunParam
+		expected   map[string]map[string]interface{}
+		shouldFail bool
+	}{
+		{
+			name:  "default http readiness",
+			param: api.RunParam{Name: "app-name", Image: "image:latest", Port: 8086, HTTPReadiness: true},
+			expected: map[string]map[string]interface{}{
+				"readinessProbe": {"httpGet": map[string]interface{}{"path": "/", "port": int64(8086)}},
+			},
+		},
+		{
+			name: "all probe kinds",
+			param: api.RunParam{
+				Name:           "app-name",
+				Image:          "image:latest",
+				Port:           8086,
+				LivenessProbe:  &api.Probe{TCP: &api.TCPProbe{}, PeriodSeconds: 10},
+				ReadinessProbe: &api.Probe{HTTP: &api.HTTPProbe{Path: "/healthz", Port: 9090}, FailureThreshold: 3},
+				StartupProbe:   &api.Probe{Exec: []string{"cat", "/tmp/started"}, InitialDelaySeconds: 5},
+			},
+			expected: map[string]map[string]interface{}{
+				"livenessProbe": {
+					"tcpSocket":     map[string]interface{}{"port": int64(8086)},
+					"periodSeconds": int64(10),
+				},
+				"readinessProbe": {
+					"httpGet":          map[string]interface{}{"path": "/healthz", "port": int64(9090)},
+					"failureThreshold": int64(3),
+				},
+				"startupProbe": {
+					"exec":                map[string]interface{}{"command": []interface{}{"cat", "/tmp/started"}},
+					"initialDelaySeconds": int64(5),
+				},
+			},
+		},
+		{
+			name:       "http readiness without port",
+			param:      api.RunParam{Name: "app-name", Image: "image:latest", HTTPReadiness: true},
+			shouldFail: true,
+		},
+		{
+			name:       "probe with multiple handlers",
+			param:      api.RunParam{Name: "app-name", Image: "image:latest", Port: 8086, LivenessProbe: &api.Probe{TCP: &api.TCPProbe{}, Exec: []string{"true"}}},
+			shouldFail: true,
+		},
+		{
+			name:       "liveness success threshold",
+			param:      api.RunParam{Name: "app-name", Image: "image:latest", Port: 8086, LivenessProbe: &api.Probe{TCP: &api.TCPProbe{}, SuccessThreshold: 2}},
+			shouldFail: true,
+		},
+	}
+
+	coord := &appCoordinator{name: "test-coord"}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := assertValidRunParam(test.param)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expecting validation error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			deployment := coord.generateDeployment(test.param)
+			containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+			container := containers[0].(map[string]interface{})
+			for _, kind := range []string{"livenessProbe", "readinessProbe", "startupProbe"} {
+				probe, ok := container[kind]
+				expected, expectOk := test.expected[kind]
+				if ok != expectOk {
+					t.Errorf("unexpected presence of %s: %v", kind, probe)
+					continue
+				}
+				if ok && !reflect.DeepEqual(probe, expected) {
+					t.Errorf("unexpected %s: %v", kind, probe)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerVolumes(t *testing.T) {
+	volumes := []api.Volume{
+		{Name: "config", MountPath: "/etc/app", ConfigMap: "app-config", ReadOnly: true},
+		{Name: "creds", MountPath: "/etc/creds/token", SubPath: "token", Secret: "app-creds"},
+		{Name: "data", MountPath: "/data", PersistentVolumeClaim: "app-data"},
+		{Name: "scratch", MountPath: "/tmp", EmptyDir: &api.EmptyDir{Medium: "Memory", SizeLimit: "64Mi"}},
+	}
+	tests := []struct {
+		name       string
+		volumes    []api.Volume
+		existing   []runtime.Object
+		shouldFail bool
+	}{
+		{
+			name:    "all volume sources",
+			volumes: volumes,
+			existing: []runtime.Object{
+				generateTestObject("v1", "ConfigMap", "appns", "app-config"),
+				generateTestObject("v1", "Secret", "appns", "app-creds"),
+			},
+		},
+		{
+			name:       "missing secret",
+			volumes:    volumes,
+			existing:   []runtime.Object{generateTestObject("v1", "ConfigMap", "appns", "app-config")},
+			shouldFail: true,
+		},
+		{
+			name:       "multiple sources",
+			volumes:    []api.Volume{{Name: "config", MountPath: "/etc/app", ConfigMap: "app-config", Secret: "app-creds"}},
+			shouldFail: true,
+		},
+		{
+			name: "duplicate mount path",
+			volumes: []api.Volume{
+				{Name: "one", MountPath: "/data", EmptyDir: &api.EmptyDir{}},
+				{Name: "two", MountPath: "/data", EmptyDir: &api.EmptyDir{}},
+			},
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme(), test.existing...)
+			coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+			coord.name = "test-coord"
+
+			param := api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:latest", Volumes: test.volumes}
+			err := coord.Run(param)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expecting run to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			savedObj, err := fakeClient.Resource(api.DeploymentsResource).Namespace("appns").Get("app-name", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			vols, _, _ := unstructured.NestedSlice(savedObj.Object, "spec", "template", "spec", "volumes")
+			if len(vols) != len(test.volumes) {
+				t.Fatal("unexpected volume count:", len(vols))
+			}
+			expectedSources := []string{"configMap", "secret", "persistentVolumeClaim", "emptyDir"}
+			for i, vol := range vols {
+				if _, ok := vol.(map[string]interface{})[expectedSources[i]]; !ok {
+					t.Errorf("expecting %s volume, got %v", expectedSources[i], vol)
+				}
+			}
+			containers, _, _ := unstructured.NestedSlice(savedObj.Object, "spec", "template", "spec", "containers")
+			mounts, _, _ := unstructured.NestedSlice(containers[0].(map[string]interface{}), "volumeMounts")
+			if len(mounts) != len(test.volumes) {
+				t.Fatal("unexpected volume mount count:", len(mounts))
+			}
+			if readOnly := mounts[0].(map[string]interface{})["readOnly"]; readOnly != true {
+				t.Error("expecting read-only mount")
+			}
+			if subPath := mounts[1].(map[string]interface{})["subPath"]; subPath != "token" {
+				t.Error("unexpected sub path:", subPath)
+			}
+		})
+	}
+}
+
+func generateTestObject(apiVersion, kind, ns, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": ns,
+			},
+		},
+	}
+}
+
+func TestRunnerCommandArgs(t *testing.T) {
+	coord := &appCoordinator{name: "test-coord"}
+	param := api.RunParam{
+		Namespace:  "appns",
+		Name:       "indexer",
+		Image:      "worker:latest",
+		Command:    []string{"/bin/worker"},
+		Args:       []string{"--role=indexer", "--advertise=$(POD_IP)"},
+		WorkingDir: "/var/lib/worker",
+	}
+	if err := assertValidRunParam(param); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment := coord.generateDeployment(param)
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	container := containers[0].(map[string]interface{})
+	command, _, _ := unstructured.NestedStringSlice(container, "command")
+	if !reflect.DeepEqual(command, param.Command) {
+		t.Error("unexpected command:", command)
+	}
+	args, _, _ := unstructured.NestedStringSlice(container, "args")
+	if !reflect.DeepEqual(args, param.Args) {
+		t.Error("unexpected args:", args)
+	}
+	if container["workingDir"] != param.WorkingDir {
+		t.Error("unexpected working dir:", container["workingDir"])
+	}
+
+	param.WorkingDir = "relative/dir"
+	if err := assertValidRunParam(param); err == nil {
+		t.Error("expecting relative working dir to be rejected")
+	}
+}
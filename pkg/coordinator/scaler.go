@@ -0,0 +1,53 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func (c *appCoordinator) Scale(param api.ScaleParam) error {
+	if err := assertValidScaleParam(param); err != nil {
+		return err
+	}
+
+	cl := c.k8sClient.Interface().Resource(api.DeploymentsResource).Namespace(param.Namespace)
+	existing, err := cl.Get(param.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if !c.isManaged(existing) {
+		return &api.ConflictError{
+			Kind:        existing.GetKind(),
+			Namespace:   param.Namespace,
+			Name:        param.Name,
+			Coordinator: c.name,
+		}
+	}
+
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, param.Replicas))
+	if _, err := cl.Patch(param.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+
+	_, err = c.waitForDeployment(param.Namespace, param.Name, param.Timeout, func(e api.DeploymentEvent) bool {
+		return e.Type != api.DeploymentEventDelete &&
+			e.Replicas == param.Replicas &&
+			e.ReadyReplicas == param.Replicas &&
+			isDeploymentObserved(e.Source)
+	})
+	return err
+}
+
+func assertValidScaleParam(param api.ScaleParam) error {
+	if param.Name == "" {
+		return errors.New("missing deployment name")
+	}
+	if param.Replicas < 0 {
+		return fmt.Errorf("invalid replicas %d: must not be negative", param.Replicas)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestScaler(t *testing.T) {
+	tests := []struct {
+		name       string
+		param      api.ScaleParam
+		readyCount int64
+		shouldFail bool
+	}{
+		{
+			name:       "scale up and become ready",
+			param:      api.ScaleParam{Namespace: "appns", Name: "app-name", Replicas: 3, Timeout: 2 * time.Second},
+			readyCount: 3,
+		},
+		{
+			name:       "scale up and time out",
+			param:      api.ScaleParam{Namespace: "appns", Name: "app-name", Replicas: 3, Timeout: 500 * time.Millisecond},
+			readyCount: 2,
+			shouldFail: true,
+		},
+		{
+			name:       "negative replicas",
+			param:      api.ScaleParam{Namespace: "appns", Name: "app-name", Replicas: -1},
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timeout := time.Duration(3 * time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			owner := &appCoordinator{name: "test-coord"}
+			deployment := owner.generateDeployment(api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:latest", Replicas: 1})
+			deployment.SetResourceVersion("1")
+			fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme(), deployment)
+			coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+			coord.name = "test-coord"
+			if err := coord.Start(ctx.Done()); err != nil {
+				t.Fatal(err)
+			}
+
+			// simulate the deployment controller reporting ready replicas,
+			// the update is waited for before the test returns
+			updateErr := make(chan error, 1)
+			go func() {
+				time.Sleep(200 * time.Millisecond)
+				cl := fakeClient.Resource(api.DeploymentsResource).Namespace("appns")
+				obj, err := cl.Get("app-name", metav1.GetOptions{})
+				if err != nil {
+					updateErr <- err
+					return
+				}
+				obj.SetResourceVersion("2")
+				unstructured.SetNestedField(obj.Object, test.readyCount, "status", "replicas")
+				unstructured.SetNestedField(obj.Object, test.readyCount, "status", "readyReplicas")
+				_, err = cl.Update(obj, metav1.UpdateOptions{})
+				updateErr <- err
+			}()
+
+			err := coord.Scale(test.param)
+			if err := <-updateErr; err != nil {
+				t.Fatal(err)
+			}
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expecting scale to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package coordinator
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const hostnameTopologyKey = "kubernetes.io/hostname"
+
+// validateScheduling validates the placement constraints of param
+func validateScheduling(param api.RunParam) field.ErrorList {
+	errs := validateLabelMap(field.NewPath("nodeSelector"), param.NodeSelector)
+	if param.NodeAffinity != nil {
+		fldPath := field.NewPath("nodeAffinity")
+		errs = append(errs, validateNodeRequirements(fldPath.Child("required"), param.NodeAffinity.Required)...)
+		for i, pref := range param.NodeAffinity.Preferred {
+			idxPath := fldPath.Child("preferred").Index(i)
+			if pref.Weight < 1 || pref.Weight > 100 {
+				errs = append(errs, field.Invalid(idxPath.Child("weight"), pref.Weight, validation.InclusiveRangeError(1, 100)))
+			}
+			if len(pref.Requirements) == 0 {
+				errs = append(errs, field.Required(idxPath.Child("requirements"), ""))
+			}
+			errs = append(errs, validateNodeRequirements(idxPath.Child("requirements"), pref.Requirements)...)
+		}
+	}
+	errs = append(errs, validatePodAffinity(field.NewPath("podAffinity"), param.PodAffinity)...)
+	errs = append(errs, validatePodAffinity(field.NewPath("podAntiAffinity"), param.PodAntiAffinity)...)
+	for i, toleration := range param.Tolerations {
+		errs = append(errs, validateToleration(field.NewPath("tolerations").Index(i), toleration)...)
+	}
+	for i, spread := range param.TopologySpread {
+		idxPath := field.NewPath("topologySpread").Index(i)
+		errs = append(errs, validateTopologyKey(idxPath.Child("topologyKey"), spread.TopologyKey)...)
+		if spread.MaxSkew < 0 {
+			errs = append(errs, field.Invalid(idxPath.Child("maxSkew"), spread.MaxSkew, "must not be negative"))
+		}
+		switch spread.WhenUnsatisfiable {
+		case "", "DoNotSchedule", "ScheduleAnyway":
+		default:
+			errs = append(errs, field.NotSupported(idxPath.Child("whenUnsatisfiable"), spread.WhenUnsatisfiable, []string{"DoNotSchedule", "ScheduleAnyway"}))
+		}
+	}
+	return errs
+}
+
+// validateLabelMap validates label style keys and values, in key order
+func validateLabelMap(fldPath *field.Path, vals map[string]string) field.ErrorList {
+	var keys []string
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs field.ErrorList
+	for _, k := range keys {
+		for _, msg := range validation.IsQualifiedName(k) {
+			errs = append(errs, field.Invalid(fldPath, k, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(vals[k]) {
+			errs = append(errs, field.Invalid(fldPath.Key(k), vals[k], msg))
+		}
+	}
+	return errs
+}
+
+func validateNodeRequirements(fldPath *field.Path, reqs []api.NodeSelectorRequirement) field.ErrorList {
+	var errs field.ErrorList
+	for i, req := range reqs {
+		idxPath := fldPath.Index(i)
+		for _, msg := range validation.IsQualifiedName(req.Key) {
+			errs = append(errs, field.Invalid(idxPath.Child("key"), req.Key, msg))
+		}
+		switch req.Operator {
+		case "In", "NotIn":
+			if len(req.Values) == 0 {
+				errs = append(errs, field.Required(idxPath.Child("values"), "operator "+req.Operator+" requires values"))
+			}
+		case "Exists", "DoesNotExist":
+			if len(req.Values) > 0 {
+				errs = append(errs, field.Forbidden(idxPath.Child("values"), "operator "+req.Operator+" takes no values"))
+			}
+		case "Gt", "Lt":
+			if len(req.Values) != 1 {
+				errs = append(errs, field.Required(idxPath.Child("values"), "operator "+req.Operator+" requires a single value"))
+			} else if _, err := strconv.ParseInt(req.Values[0], 10, 64); err != nil {
+				errs = append(errs, field.Invalid(idxPath.Child("values").Index(0), req.Values[0], "must be an integer"))
+			}
+		default:
+			errs = append(errs, field.NotSupported(idxPath.Child("operator"), req.Operator, []string{"In", "NotIn", "Exists", "DoesNotExist", "Gt", "Lt"}))
+		}
+	}
+	return errs
+}
+
+func validatePodAffinity(fldPath *field.Path, terms []api.PodAffinityTerm) field.ErrorList {
+	var errs field.ErrorList
+	for i, term := range terms {
+		idxPath := fldPath.Index(i)
+		if len(term.Labels) == 0 {
+			errs = append(errs, field.Required(idxPath.Child("labels"), ""))
+		}
+		errs = append(errs, validateLabelMap(idxPath.Child("labels"), term.Labels)...)
+		errs = append(errs, validateTopologyKey(idxPath.Child("topologyKey"), term.TopologyKey)...)
+		if term.Weight < 0 || term.Weight > 100 {
+			errs = append(errs, field.Invalid(idxPath.Child("weight"), term.Weight, validation.InclusiveRangeError(0, 100)))
+		}
+	}
+	return errs
+}
+
+func validateTopologyKey(fldPath *field.Path, key string) field.ErrorList {
+	if key == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsQualifiedName(key) {
+		errs = append(errs, field.Invalid(fldPath, key, msg))
+	}
+	return errs
+}
+
+func validateToleration(fldPath *field.Path, toleration api.Toleration) field.ErrorList {
+	var errs field.ErrorList
+	switch toleration.Operator {
+	case "", "Equal":
+		if toleration.Key == "" {
+			errs = append(errs, field.Required(fldPath.Child("key"), "required by the Equal operator"))
+		}
+	case "Exists":
+		if toleration.Value != "" {
+			errs = append(errs, field.Forbidden(fldPath.Child("value"), "operator Exists takes no value"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("operator"), toleration.Operator, []string{"Equal", "Exists"}))
+	}
+	switch toleration.Effect {
+	case "", "NoSchedule", "PreferNoSchedule", "NoExecute":
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("effect"), toleration.Effect, []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}))
+	}
+	if toleration.TolerationSeconds != nil && toleration.Effect != "NoExecute" {
+		errs = append(errs, field.Forbidden(fldPath.Child("tolerationSeconds"), "requires the NoExecute effect"))
+	}
+	return errs
+}
+
+// generateScheduling adds the placement constraints of param to podSpec
+func (c *appCoordinator) generateScheduling(param api.RunParam, podSpec map[string]interface{}) map[string]interface{} {
+	if len(param.NodeSelector) > 0 {
+		podSpec["nodeSelector"] = toInterfaceMap(param.NodeSelector)
+	}
+
+	antiAffinity := append([]api.PodAffinityTerm{}, param.PodAntiAffinity...)
+	if param.SpreadAcrossNodes {
+		antiAffinity = append(antiAffinity, api.PodAffinityTerm{
+			Labels:      c.selectorLabels(param.Name),
+			TopologyKey: hostnameTopologyKey,
+			Weight:      100,
+		})
+	}
+	affinity := make(map[string]interface{})
+	if param.NodeAffinity != nil {
+		affinity["nodeAffinity"] = generateNodeAffinity(param.NodeAffinity)
+	}
+	if len(param.PodAffinity) > 0 {
+		affinity["podAffinity"] = generatePodAffinity(param.PodAffinity)
+	}
+	if len(antiAffinity) > 0 {
+		affinity["podAntiAffinity"] = generatePodAffinity(antiAffinity)
+	}
+	if len(affinity) > 0 {
+		podSpec["affinity"] = affinity
+	}
+
+	if len(param.Tolerations) > 0 {
+		var tolerations []interface{}
+		for _, toleration := range param.Tolerations {
+			tolerations = append(tolerations, generateToleration(toleration))
+		}
+		podSpec["tolerations"] = tolerations
+	}
+
+	if len(param.TopologySpread) > 0 {
+		var constraints []interface{}
+		for _, spread := range param.TopologySpread {
+			maxSkew := spread.MaxSkew
+			if maxSkew == 0 {
+				maxSkew = 1
+			}
+			action := spread.WhenUnsatisfiable
+			if action == "" {
+				action = "DoNotSchedule"
+			}
+			constraints = append(constraints, map[string]interface{}{
+				"maxSkew":           maxSkew,
+				"topologyKey":       spread.TopologyKey,
+				"whenUnsatisfiable": action,
+				"labelSelector": map[string]interface{}{
+					"matchLabels": c.generateSelector(param),
+				},
+			})
+		}
+		podSpec["topologySpreadConstraints"] = constraints
+	}
+	return podSpec
+}
+
+func generateNodeAffinity(affinity *api.NodeAffinity) map[string]interface{} {
+	result := make(map[string]interface{})
+	if len(affinity.Required) > 0 {
+		result["requiredDuringSchedulingIgnoredDuringExecution"] = map[string]interface{}{
+			"nodeSelectorTerms": []interface{}{
+				map[string]interface{}{
+					"matchExpressions": generateNodeRequirements(affinity.Required),
+				},
+			},
+		}
+	}
+	if len(affinity.Preferred) > 0 {
+		var preferred []interface{}
+		for _, pref := range affinity.Preferred {
+			preferred = append(preferred, map[string]interface{}{
+				"weight": pref.Weight,
+				"preference": map[string]interface{}{
+					"matchExpressions": generateNodeRequirements(pref.Requirements),
+				},
+			})
+		}
+		result["preferredDuringSchedulingIgnoredDuringExecution"] = preferred
+	}
+	return result
+}
+
+func generateNodeRequirements(reqs []api.NodeSelectorRequirement) []interface{} {
+	var result []interface{}
+	for _, req := range reqs {
+		expr := map[string]interface{}{
+			"key":      req.Key,
+			"operator": req.Operator,
+		}
+		if len(req.Values) > 0 {
+			expr["values"] = toInterfaceSlice(req.Values)
+		}
+		result = append(result, expr)
+	}
+	return result
+}
+
+// generatePodAffinity splits terms into required
+// (zero weight) and preferred scheduling terms
+func generatePodAffinity(terms []api.PodAffinityTerm) map[string]interface{} {
+	var required, preferred []interface{}
+	for _, term := range terms {
+		affinityTerm := map[string]interface{}{
+			"labelSelector": map[string]interface{}{
+				"matchLabels": toInterfaceMap(term.Labels),
+			},
+			"topologyKey": term.TopologyKey,
+		}
+		if len(term.Namespaces) > 0 {
+			affinityTerm["namespaces"] = toInterfaceSlice(term.Namespaces)
+		}
+		if term.Weight == 0 {
+			required = append(required, affinityTerm)
+			continue
+		}
+		preferred = append(preferred, map[string]interface{}{
+			"weight":          term.Weight,
+			"podAffinityTerm": affinityTerm,
+		})
+	}
+
+	result := make(map[string]interface{})
+	if len(required) > 0 {
+		result["requiredDuringSchedulingIgnoredDuringExecution"] = required
+	}
+	if len(preferred) > 0 {
+		result["preferredDuringSchedulingIgnoredDuringExecution"] = preferred
+	}
+	return result
+}
+
+func generateToleration(toleration api.Toleration) map[string]interface{} {
+	result := make(map[string]interface{})
+	if toleration.Key != "" {
+		result["key"] = toleration.Key
+	}
+	if toleration.Operator != "" {
+		result["operator"] = toleration.Operator
+	}
+	if toleration.Value != "" {
+		result["value"] = toleration.Value
+	}
+	if toleration.Effect != "" {
+		result["effect"] = toleration.Effect
+	}
+	if toleration.TolerationSeconds != nil {
+		result["tolerationSeconds"] = *toleration.TolerationSeconds
+	}
+	return result
+}
+
+func toInterfaceMap(vals map[string]string) map[string]interface{} {
+	result := make(map[string]interface{})
+	for k, v := range vals {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,205 @@
+package coordinator
+
+import (
+	"testing"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSchedulingGenerate(t *testing.T) {
+	seconds := int64(30)
+	tests := []struct {
+		name  string
+		param api.RunParam
+		test  func(*testing.T, map[string]interface{})
+	}{
+		{
+			name: "node affinity",
+			param: api.RunParam{
+				NodeAffinity: &api.NodeAffinity{
+					Required: []api.NodeSelectorRequirement{
+						{Key: "kubernetes.io/arch", Operator: "In", Values: []string{"amd64"}},
+					},
+					Preferred: []api.PreferredNodeSelector{
+						{Weight: 10, Requirements: []api.NodeSelectorRequirement{{Key: "disk", Operator: "Exists"}}},
+					},
+				},
+			},
+			test: func(t *testing.T, spec map[string]interface{}) {
+				terms, _, _ := unstructured.NestedSlice(spec, "affinity", "nodeAffinity", "requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms")
+				if len(terms) != 1 {
+					t.Fatal("unexpected node selector terms:", terms)
+				}
+				exprs, _, _ := unstructured.NestedSlice(terms[0].(map[string]interface{}), "matchExpressions")
+				if len(exprs) != 1 || exprs[0].(map[string]interface{})["key"] != "kubernetes.io/arch" {
+					t.Error("unexpected match expressions:", exprs)
+				}
+				preferred, _, _ := unstructured.NestedSlice(spec, "affinity", "nodeAffinity", "preferredDuringSchedulingIgnoredDuringExecution")
+				if len(preferred) != 1 || preferred[0].(map[string]interface{})["weight"] != int64(10) {
+					t.Error("unexpected node preferences:", preferred)
+				}
+			},
+		},
+		{
+			name: "pod affinity",
+			param: api.RunParam{
+				PodAffinity: []api.PodAffinityTerm{
+					{Labels: map[string]string{"app": "cache"}, TopologyKey: "topology.kubernetes.io/zone"},
+					{Labels: map[string]string{"app": "db"}, TopologyKey: "kubernetes.io/hostname", Weight: 50},
+				},
+			},
+			test: func(t *testing.T, spec map[string]interface{}) {
+				required, _, _ := unstructured.NestedSlice(spec, "affinity", "podAffinity", "requiredDuringSchedulingIgnoredDuringExecution")
+				if len(required) != 1 {
+					t.Fatal("unexpected required terms:", required)
+				}
+				app, _, _ := unstructured.NestedString(required[0].(map[string]interface{}), "labelSelector", "matchLabels", "app")
+				if app != "cache" {
+					t.Error("unexpected required term labels:", required[0])
+				}
+				preferred, _, _ := unstructured.NestedSlice(spec, "affinity", "podAffinity", "preferredDuringSchedulingIgnoredDuringExecution")
+				if len(preferred) != 1 {
+					t.Fatal("unexpected preferred terms:", preferred)
+				}
+				key, _, _ := unstructured.NestedString(preferred[0].(map[string]interface{}), "podAffinityTerm", "topologyKey")
+				if key != "kubernetes.io/hostname" {
+					t.Error("unexpected topology key:", key)
+				}
+			},
+		},
+		{
+			name:  "spread across nodes",
+			param: api.RunParam{SpreadAcrossNodes: true},
+			test: func(t *testing.T, spec map[string]interface{}) {
+				preferred, _, _ := unstructured.NestedSlice(spec, "affinity", "podAntiAffinity", "preferredDuringSchedulingIgnoredDuringExecution")
+				if len(preferred) != 1 {
+					t.Fatal("unexpected anti-affinity terms:", preferred)
+				}
+				term := preferred[0].(map[string]interface{})
+				labels, _, _ := unstructured.NestedStringMap(term, "podAffinityTerm", "labelSelector", "matchLabels")
+				if labels["app"] != "app" || labels["coordinator"] != "test-coord" {
+					t.Error("unexpected anti-affinity labels:", labels)
+				}
+				key, _, _ := unstructured.NestedString(term, "podAffinityTerm", "topologyKey")
+				if key != hostnameTopologyKey {
+					t.Error("unexpected topology key:", key)
+				}
+			},
+		},
+		{
+			name: "tolerations",
+			param: api.RunParam{
+				Tolerations: []api.Toleration{
+					{Key: "dedicated", Value: "workers", Effect: "NoSchedule"},
+					{Key: "node.kubernetes.io/unreachable", Operator: "Exists", Effect: "NoExecute", TolerationSeconds: &seconds},
+				},
+			},
+			test: func(t *testing.T, spec map[string]interface{}) {
+				tolerations, _, _ := unstructured.NestedSlice(spec, "tolerations")
+				if len(tolerations) != 2 {
+					t.Fatal("unexpected tolerations:", tolerations)
+				}
+				if tolerations[1].(map[string]interface{})["tolerationSeconds"] != int64(30) {
+					t.Error("unexpected toleration seconds:", tolerations[1])
+				}
+			},
+		},
+		{
+			name: "topology spread",
+			param: api.RunParam{
+				TopologySpread: []api.TopologySpreadConstraint{{TopologyKey: "topology.kubernetes.io/zone"}},
+			},
+			test: func(t *testing.T, spec map[string]interface{}) {
+				constraints, _, _ := unstructured.NestedSlice(spec, "topologySpreadConstraints")
+				if len(constraints) != 1 {
+					t.Fatal("unexpected spread constraints:", constraints)
+				}
+				constraint := constraints[0].(map[string]interface{})
+				if constraint["maxSkew"] != int64(1) || constraint["whenUnsatisfiable"] != "DoNotSchedule" {
+					t.Error("unexpected spread defaults:", constraint)
+				}
+				app, _, _ := unstructured.NestedString(constraint, "labelSelector", "matchLabels", "app")
+				if app != "app" {
+					t.Error("unexpected spread selector:", constraint)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			coord := &appCoordinator{name: "test-coord"}
+			param := test.param
+			param.Name = "app"
+			param.Image = "image:latest"
+			if err := assertValidRunParam(param); err != nil {
+				t.Fatal(err)
+			}
+			deployment := coord.generateDeployment(param)
+			spec, _, _ := unstructured.NestedMap(deployment.Object, "spec", "template", "spec")
+			test.test(t, spec)
+		})
+	}
+}
+
+func TestSchedulingValidation(t *testing.T) {
+	seconds := int64(30)
+	tests := []struct {
+		name  string
+		param api.RunParam
+	}{
+		{
+			name:  "bad node selector",
+			param: api.RunParam{NodeSelector: map[string]string{"disk": "ssd fast"}},
+		},
+		{
+			name: "in without values",
+			param: api.RunParam{NodeAffinity: &api.NodeAffinity{
+				Required: []api.NodeSelectorRequirement{{Key: "disk", Operator: "In"}},
+			}},
+		},
+		{
+			name: "gt not integer",
+			param: api.RunParam{NodeAffinity: &api.NodeAffinity{
+				Required: []api.NodeSelectorRequirement{{Key: "cores", Operator: "Gt", Values: []string{"many"}}},
+			}},
+		},
+		{
+			name: "preference weight",
+			param: api.RunParam{NodeAffinity: &api.NodeAffinity{
+				Preferred: []api.PreferredNodeSelector{
+					{Weight: 0, Requirements: []api.NodeSelectorRequirement{{Key: "disk", Operator: "Exists"}}},
+				},
+			}},
+		},
+		{
+			name:  "pod affinity without topology",
+			param: api.RunParam{PodAffinity: []api.PodAffinityTerm{{Labels: map[string]string{"app": "db"}}}},
+		},
+		{
+			name:  "pod anti-affinity weight",
+			param: api.RunParam{PodAntiAffinity: []api.PodAffinityTerm{{Labels: map[string]string{"app": "db"}, TopologyKey: "zone", Weight: 101}}},
+		},
+		{
+			name:  "toleration exists with value",
+			param: api.RunParam{Tolerations: []api.Toleration{{Key: "dedicated", Operator: "Exists", Value: "workers"}}},
+		},
+		{
+			name:  "toleration seconds without NoExecute",
+			param: api.RunParam{Tolerations: []api.Toleration{{Key: "dedicated", Effect: "NoSchedule", TolerationSeconds: &seconds}}},
+		},
+		{
+			name:  "spread action",
+			param: api.RunParam{TopologySpread: []api.TopologySpreadConstraint{{TopologyKey: "zone", WhenUnsatisfiable: "Never"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if errs := validateScheduling(test.param); len(errs) == 0 {
+				t.Fatal("expecting failure, got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package coordinator
+
+import (
+	"strings"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	seccompRuntimeDefault = "RuntimeDefault"
+	seccompUnconfined     = "Unconfined"
+	seccompLocalhost      = "Localhost"
+)
+
+func validateSecurityContext(param api.RunParam) field.ErrorList {
+	sc := param.SecurityContext
+	if sc == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	fldPath := field.NewPath("securityContext")
+
+	for _, id := range []struct {
+		name  string
+		value *int64
+	}{
+		{"runAsUser", sc.RunAsUser},
+		{"runAsGroup", sc.RunAsGroup},
+		{"fsGroup", sc.FSGroup},
+	} {
+		if id.value != nil && *id.value < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child(id.name), *id.value, "must not be negative"))
+		}
+	}
+	if isTrue(sc.RunAsNonRoot) && sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("runAsUser"), *sc.RunAsUser, "must not be 0 when running as non-root"))
+	}
+	for i, capability := range sc.DropCapabilities {
+		errs = append(errs, validateCapability(fldPath.Child("dropCapabilities").Index(i), capability)...)
+	}
+	for i, capability := range sc.AddCapabilities {
+		errs = append(errs, validateCapability(fldPath.Child("addCapabilities").Index(i), capability)...)
+	}
+	profileType, profile := parseSeccompProfile(sc.SeccompProfile)
+	switch profileType {
+	case "":
+	case seccompRuntimeDefault, seccompUnconfined:
+		if profile != "" {
+			errs = append(errs, field.Invalid(fldPath.Child("seccompProfile"), sc.SeccompProfile, "only Localhost profiles name a profile"))
+		}
+	case seccompLocalhost:
+		if profile == "" {
+			errs = append(errs, field.Invalid(fldPath.Child("seccompProfile"), sc.SeccompProfile, "must name a profile, e.g. Localhost/profiles/worker.json"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("seccompProfile"), sc.SeccompProfile, []string{seccompRuntimeDefault, seccompUnconfined, seccompLocalhost + "/<profile>"}))
+	}
+
+	switch sc.Preset {
+	case "":
+	case api.SecurityPresetRestricted:
+		errs = append(errs, validateRestricted(fldPath, sc)...)
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("preset"), sc.Preset, []string{api.SecurityPresetRestricted}))
+	}
+	return errs
+}
+
+// validateRestricted rejects the explicit settings that
+// would violate the restricted Pod Security Standard
+func validateRestricted(fldPath *field.Path, sc *api.SecurityContext) field.ErrorList {
+	var errs field.ErrorList
+	const reason = "not allowed by the restricted preset"
+	if sc.RunAsNonRoot != nil && !*sc.RunAsNonRoot {
+		errs = append(errs, field.Forbidden(fldPath.Child("runAsNonRoot"), reason))
+	}
+	if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+		errs = append(errs, field.Forbidden(fldPath.Child("runAsUser"), reason))
+	}
+	if isTrue(sc.AllowPrivilegeEscalation) {
+		errs = append(errs, field.Forbidden(fldPath.Child("allowPrivilegeEscalation"), reason))
+	}
+	if len(sc.DropCapabilities) > 0 && !containsString(sc.DropCapabilities, "ALL") {
+		errs = append(errs, field.Invalid(fldPath.Child("dropCapabilities"), sc.DropCapabilities, "must include ALL with the restricted preset"))
+	}
+	for i, capability := range sc.AddCapabilities {
+		if capability != "NET_BIND_SERVICE" {
+			errs = append(errs, field.Forbidden(fldPath.Child("addCapabilities").Index(i), reason))
+		}
+	}
+	if profileType, _ := parseSeccompProfile(sc.SeccompProfile); profileType == seccompUnconfined {
+		errs = append(errs, field.Forbidden(fldPath.Child("seccompProfile"), reason))
+	}
+	return errs
+}
+
+func validateCapability(fldPath *field.Path, capability string) field.ErrorList {
+	if capability == "" || strings.ToUpper(capability) != capability || strings.HasPrefix(capability, "CAP_") {
+		return field.ErrorList{field.Invalid(fldPath, capability, "must be an upper case capability name without the CAP_ prefix, e.g. NET_ADMIN")}
+	}
+	return nil
+}
+
+// parseSeccompProfile splits profile into its type and, for
+// Localhost profiles, the path of the profile
+func parseSeccompProfile(profile string) (string, string) {
+	parts := strings.SplitN(profile, "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return parts[0], ""
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+// withSecurityDefaults returns a copy of sc where the fields
+// left unset are defaulted by its preset
+func withSecurityDefaults(sc *api.SecurityContext) *api.SecurityContext {
+	if sc == nil || sc.Preset != api.SecurityPresetRestricted {
+		return sc
+	}
+	result := *sc
+	if result.RunAsNonRoot == nil {
+		nonRoot := true
+		result.RunAsNonRoot = &nonRoot
+	}
+	if result.AllowPrivilegeEscalation == nil {
+		escalation := false
+		result.AllowPrivilegeEscalation = &escalation
+	}
+	if len(result.DropCapabilities) == 0 {
+		result.DropCapabilities = []string{"ALL"}
+	}
+	if result.SeccompProfile == "" {
+		result.SeccompProfile = seccompRuntimeDefault
+	}
+	return &result
+}
+
+// generateSecurityContext sets the pod security context of podSpec
+// and the security context of each of its containers
+func generateSecurityContext(sc *api.SecurityContext, podSpec map[string]interface{}) map[string]interface{} {
+	if sc == nil {
+		return podSpec
+	}
+
+	if podContext := generatePodSecurityContext(sc); len(podContext) > 0 {
+		podSpec["securityContext"] = podContext
+	}
+	if len(generateContainerSecurityContext(sc)) == 0 {
+		return podSpec
+	}
+	for _, key := range []string{"initContainers", "containers"} {
+		containers, _ := podSpec[key].([]interface{})
+		for _, container := range containers {
+			// each container gets its own copy
+			container.(map[string]interface{})["securityContext"] = generateContainerSecurityContext(sc)
+		}
+	}
+	return podSpec
+}
+
+func generatePodSecurityContext(sc *api.SecurityContext) map[string]interface{} {
+	result := make(map[string]interface{})
+	if sc.RunAsUser != nil {
+		result["runAsUser"] = *sc.RunAsUser
+	}
+	if sc.RunAsGroup != nil {
+		result["runAsGroup"] = *sc.RunAsGroup
+	}
+	if sc.RunAsNonRoot != nil {
+		result["runAsNonRoot"] = *sc.RunAsNonRoot
+	}
+	if sc.FSGroup != nil {
+		result["fsGroup"] = *sc.FSGroup
+	}
+	if sc.SeccompProfile != "" {
+		profileType, profile := parseSeccompProfile(sc.SeccompProfile)
+		seccomp := map[string]interface{}{"type": profileType}
+		if profile != "" {
+			seccomp["localhostProfile"] = profile
+		}
+		result["seccompProfile"] = seccomp
+	}
+	return result
+}
+
+func generateContainerSecurityContext(sc *api.SecurityContext) map[string]interface{} {
+	result := make(map[string]interface{})
+	if sc.ReadOnlyRootFilesystem {
+		result["readOnlyRootFilesystem"] = true
+	}
+	if sc.AllowPrivilegeEscalation != nil {
+		result["allowPrivilegeEscalation"] = *sc.AllowPrivilegeEscalation
+	}
+	capabilities := make(map[string]interface{})
+	if len(sc.DropCapabilities) > 0 {
+		capabilities["drop"] = toInterfaceSlice(sc.DropCapabilities)
+	}
+	if len(sc.AddCapabilities) > 0 {
+		capabilities["add"] = toInterfaceSlice(sc.AddCapabilities)
+	}
+	if len(capabilities) > 0 {
+		result["capabilities"] = capabilities
+	}
+	return result
+}
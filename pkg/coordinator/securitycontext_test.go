@@ -0,0 +1,147 @@
+package coordinator
+
+import (
+	"testing"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestSecurityContextRender(t *testing.T) {
+	uid := int64(1000)
+	root := int64(0)
+	escalate := true
+	nonRoot := false
+	tests := []struct {
+		name       string
+		sc         *api.SecurityContext
+		test       func(*testing.T, map[string]interface{})
+		shouldFail bool
+	}{
+		{
+			name: "restricted preset",
+			sc:   &api.SecurityContext{Preset: api.SecurityPresetRestricted, RunAsUser: &uid, FSGroup: &uid},
+			test: func(t *testing.T, podSpec map[string]interface{}) {
+				if v, _, _ := unstructured.NestedBool(podSpec, "securityContext", "runAsNonRoot"); !v {
+					t.Error("expecting runAsNonRoot")
+				}
+				if v, _, _ := unstructured.NestedInt64(podSpec, "securityContext", "fsGroup"); v != 1000 {
+					t.Error("unexpected fsGroup:", v)
+				}
+				if v, _, _ := unstructured.NestedString(podSpec, "securityContext", "seccompProfile", "type"); v != "RuntimeDefault" {
+					t.Error("unexpected seccomp profile:", v)
+				}
+				// every container must pass the standard
+				for _, key := range []string{"initContainers", "containers"} {
+					containers, _, _ := unstructured.NestedSlice(podSpec, key)
+					for _, c := range containers {
+						container := c.(map[string]interface{})
+						escalation, ok, _ := unstructured.NestedBool(container, "securityContext", "allowPrivilegeEscalation")
+						if !ok || escalation {
+							t.Error("expecting privilege escalation disabled on", container["name"])
+						}
+						drop, _, _ := unstructured.NestedStringSlice(container, "securityContext", "capabilities", "drop")
+						if len(drop) != 1 || drop[0] != "ALL" {
+							t.Error("unexpected dropped capabilities on", container["name"], drop)
+						}
+					}
+				}
+			},
+		},
+		{
+			name: "explicit fields",
+			sc: &api.SecurityContext{
+				RunAsUser:              &root,
+				ReadOnlyRootFilesystem: true,
+				AddCapabilities:        []string{"NET_ADMIN"},
+				SeccompProfile:         "Localhost/profiles/worker.json",
+			},
+			test: func(t *testing.T, podSpec map[string]interface{}) {
+				if _, ok, _ := unstructured.NestedFieldNoCopy(podSpec, "securityContext", "runAsNonRoot"); ok {
+					t.Error("unexpected runAsNonRoot")
+				}
+				if v, _, _ := unstructured.NestedString(podSpec, "securityContext", "seccompProfile", "localhostProfile"); v != "profiles/worker.json" {
+					t.Error("unexpected localhost profile:", v)
+				}
+				containers, _, _ := unstructured.NestedSlice(podSpec, "containers")
+				container := containers[0].(map[string]interface{})
+				if v, _, _ := unstructured.NestedBool(container, "securityContext", "readOnlyRootFilesystem"); !v {
+					t.Error("expecting read-only root filesystem")
+				}
+				add, _, _ := unstructured.NestedStringSlice(container, "securityContext", "capabilities", "add")
+				if len(add) != 1 || add[0] != "NET_ADMIN" {
+					t.Error("unexpected added capabilities:", add)
+				}
+			},
+		},
+		{
+			name:       "restricted as root",
+			sc:         &api.SecurityContext{Preset: api.SecurityPresetRestricted, RunAsUser: &root},
+			shouldFail: true,
+		},
+		{
+			name:       "restricted with escalation",
+			sc:         &api.SecurityContext{Preset: api.SecurityPresetRestricted, AllowPrivilegeEscalation: &escalate},
+			shouldFail: true,
+		},
+		{
+			name:       "restricted with root allowed",
+			sc:         &api.SecurityContext{Preset: api.SecurityPresetRestricted, RunAsNonRoot: &nonRoot},
+			shouldFail: true,
+		},
+		{
+			name:       "restricted with capabilities",
+			sc:         &api.SecurityContext{Preset: api.SecurityPresetRestricted, AddCapabilities: []string{"SYS_ADMIN"}},
+			shouldFail: true,
+		},
+		{
+			name:       "restricted unconfined",
+			sc:         &api.SecurityContext{Preset: api.SecurityPresetRestricted, SeccompProfile: "Unconfined"},
+			shouldFail: true,
+		},
+		{
+			name:       "unknown preset",
+			sc:         &api.SecurityContext{Preset: "baseline"},
+			shouldFail: true,
+		},
+		{
+			name:       "bad capability",
+			sc:         &api.SecurityContext{DropCapabilities: []string{"cap_net_raw"}},
+			shouldFail: true,
+		},
+		{
+			name:       "localhost without profile",
+			sc:         &api.SecurityContext{SeccompProfile: "Localhost"},
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			coord := newCoord(client.NewFromDynamicClient("", fake.NewSimpleDynamicClient(runtime.NewScheme())))
+			coord.name = "test-coord"
+			manifest, err := coord.Render(api.RunParam{
+				Namespace:       "appns",
+				Name:            "worker",
+				Image:           "image:latest",
+				InitContainers:  []api.Container{{Name: "init", Image: "init:latest"}},
+				Sidecars:        []api.Container{{Name: "proxy", Image: "proxy:latest"}},
+				SecurityContext: test.sc,
+			})
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expecting failure, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			podSpec, _, _ := unstructured.NestedMap(manifest.Objects[0].Object, "spec", "template", "spec")
+			test.test(t, podSpec)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package coordinator
+
+import (
+	"fmt"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// serviceAnnotation records, on a workload, the DNS name
+// of the service created alongside it
+const serviceAnnotation = "coordinator/service"
+
+func validateService(param api.RunParam) field.ErrorList {
+	fldPath := field.NewPath("service")
+	switch param.Service {
+	case api.ServiceNone:
+		return nil
+	case api.ServiceClusterIP, api.ServiceHeadless:
+	default:
+		return field.ErrorList{field.NotSupported(fldPath, param.Service, []string{string(api.ServiceClusterIP), string(api.ServiceHeadless)})}
+	}
+	if param.Service == api.ServiceClusterIP && len(workerPorts(param)) == 0 {
+		return field.ErrorList{field.Required(field.NewPath("port"), "a port is required to expose the workload")}
+	}
+	return nil
+}
+
+// syncService creates or updates the service requested by param.
+// The applied service, if any, is returned.
+func (c *appCoordinator) syncService(param api.RunParam, dryRun bool) (*unstructured.Unstructured, error) {
+	if param.Service == api.ServiceNone {
+		return nil, nil
+	}
+	svc, _, err := c.apply(api.ServicesResource, c.generateService(param), dryRun)
+	return svc, err
+}
+
+// isServiceTypeChanged returns true if the services switch between
+// ClusterIP and Headless, the cluster IP of a service being immutable
+func isServiceTypeChanged(existing, desired *unstructured.Unstructured) bool {
+	isHeadless := func(obj *unstructured.Unstructured) bool {
+		clusterIP, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP")
+		return clusterIP == "None"
+	}
+	return isHeadless(existing) != isHeadless(desired)
+}
+
+func (c *appCoordinator) generateService(param api.RunParam) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"type":     "ClusterIP",
+		"selector": c.generateSelector(param),
+	}
+	// headless services may omit ports
+	var ports []interface{}
+	for _, port := range workerPorts(param) {
+		ports = append(ports, map[string]interface{}{
+			"name":       port.Name,
+			"protocol":   protocolOrDefault(port.Protocol),
+			"port":       port.Port,
+			"targetPort": port.Port,
+		})
+	}
+	if len(ports) > 0 {
+		spec["ports"] = ports
+	}
+	if param.Service == api.ServiceHeadless {
+		spec["clusterIP"] = "None"
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   c.generateCompanionMeta(param),
+			"spec":       spec,
+		},
+	}
+}
+
+// generateCompanionMeta returns the metadata of the objects
+// created alongside the workload, named after it
+func (c *appCoordinator) generateCompanionMeta(param api.RunParam) map[string]interface{} {
+	labels := c.generateLabels(param)
+	meta := map[string]interface{}{
+		"name":      param.Name,
+		"namespace": param.Namespace,
+		"labels":    labels,
+		"annotations": map[string]interface{}{
+			labelsAnnotation: generateLabelsAnnotation(labels),
+		},
+	}
+	if refs := c.generateOwnerReferences(param); refs != nil {
+		meta["ownerReferences"] = refs
+	}
+	return meta
+}
+
+// serviceDNSName returns the namespace-qualified DNS name of the
+// service, which resolves from any pod using the cluster search domains
+func serviceDNSName(name, namespace string) string {
+	return fmt.Sprintf("%s.%s.svc", name, namespace)
+}
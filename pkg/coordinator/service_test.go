@@ -0,0 +1,196 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestService(t *testing.T) {
+	tests := []struct {
+		name      string
+		param     api.RunParam
+		clusterIP string
+	}{
+		{
+			name:  "cluster ip service",
+			param: api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:latest", Port: 8086, Service: api.ServiceClusterIP},
+		},
+		{
+			name:      "headless service",
+			param:     api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:latest", Port: 8086, Service: api.ServiceHeadless},
+			clusterIP: "None",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timeout := time.Duration(3 * time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+			coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+			coord.name = "test-coord"
+
+			dnsCh := make(chan string, 1)
+			coord.OnDeploymentEvent(func(e api.DeploymentEvent) {
+				if e.Type == api.DeploymentEventNew {
+					dnsCh <- e.ServiceDNS
+				}
+			})
+			if err := coord.Start(ctx.Done()); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := coord.Run(test.param); err != nil {
+				t.Fatal(err)
+			}
+
+			svcClient := fakeClient.Resource(api.ServicesResource).Namespace("appns")
+			svc, err := svcClient.Get("app-name", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			selector, _, _ := unstructured.NestedStringMap(svc.Object, "spec", "selector")
+			for k, v := range coord.selectorLabels("app-name") {
+				if selector[k] != v {
+					t.Errorf("unexpected service selector %s=%s", k, selector[k])
+				}
+			}
+			ports, _, _ := unstructured.NestedSlice(svc.Object, "spec", "ports")
+			if len(ports) != 1 || ports[0].(map[string]interface{})["port"] != int64(8086) {
+				t.Error("unexpected service ports:", ports)
+			}
+			clusterIP, _, _ := unstructured.NestedString(svc.Object, "spec", "clusterIP")
+			if clusterIP != test.clusterIP {
+				t.Error("unexpected cluster IP:", clusterIP)
+			}
+
+			select {
+			case dns := <-dnsCh:
+				if dns != "app-name.appns.svc" {
+					t.Error("unexpected service DNS name:", dns)
+				}
+			case <-ctx.Done():
+				t.Fatal("deployment event not received")
+			}
+
+			// service removed when no longer requested
+			param := test.param
+			param.Service = api.ServiceNone
+			if err := coord.Run(param); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := svcClient.Get("app-name", metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Error("expecting service to be removed, got:", err)
+			}
+			deployment, err := fakeClient.Resource(api.DeploymentsResource).Namespace("appns").Get("app-name", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := deployment.GetAnnotations()[serviceAnnotation]; ok {
+				t.Error("expecting service annotation to be removed")
+			}
+
+			// service deleted with the workload
+			if err := coord.Run(test.param); err != nil {
+				t.Fatal(err)
+			}
+			if err := coord.Delete(api.DeleteParam{Namespace: "appns", Name: "app-name"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := svcClient.Get("app-name", metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Error("expecting service to be deleted, got:", err)
+			}
+		})
+	}
+}
+
+func TestServiceTypeChange(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+
+	// no service is looked up when none was requested
+	param := api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:latest", Port: 8086}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range fakeClient.Actions() {
+		if action.GetResource() == api.ServicesResource {
+			t.Error("unexpected service action:", action)
+		}
+	}
+
+	// the cluster IP allocated by the server is kept
+	svcClient := fakeClient.Resource(api.ServicesResource).Namespace("appns")
+	param.Service = api.ServiceClusterIP
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := svcClient.Get("app-name", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unstructured.SetNestedField(svc.Object, "10.0.0.12", "spec", "clusterIP")
+	if _, err := svcClient.Update(svc, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	svc, err = svcClient.Get("app-name", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clusterIP, _, _ := unstructured.NestedString(svc.Object, "spec", "clusterIP"); clusterIP != "10.0.0.12" {
+		t.Error("unexpected cluster IP:", clusterIP)
+	}
+
+	// the cluster IP cannot change, the service is recreated
+	svc.SetUID("svc-uid")
+	if _, err := svcClient.Update(svc, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	param.Service = api.ServiceHeadless
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+	svc, err = svcClient.Get("app-name", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.GetUID() == "svc-uid" {
+		t.Error("expecting service to be recreated")
+	}
+	if clusterIP, _, _ := unstructured.NestedString(svc.Object, "spec", "clusterIP"); clusterIP != "None" {
+		t.Error("unexpected cluster IP:", clusterIP)
+	}
+}
+
+func TestAssertValidService(t *testing.T) {
+	if err := assertValidRunParam(api.RunParam{Name: "app-name", Image: "image:latest", Service: api.ServiceClusterIP}); err == nil {
+		t.Error("expecting service without port to be rejected")
+	}
+	if err := assertValidRunParam(api.RunParam{Name: "app-name", Image: "image:latest", Port: 8086, Service: "NodePort"}); err == nil {
+		t.Error("expecting unsupported service type to be rejected")
+	}
+}
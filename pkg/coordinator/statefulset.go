@@ -0,0 +1,275 @@
+package coordinator
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/controller"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func (c *appCoordinator) OnStatefulSetEvent(e api.StatefulSetEventFunc) api.Coordinator {
+	c.stsEventFunc = e
+	return c
+}
+
+// validateStatefulSet validates the StatefulSet specific fields of param
+func validateStatefulSet(param api.RunParam) field.ErrorList {
+	var errs field.ErrorList
+	if param.Kind != api.WorkloadStatefulSet {
+		if len(param.VolumeClaims) > 0 {
+			errs = append(errs, field.Forbidden(field.NewPath("volumeClaims"), "only supported by StatefulSets"))
+		}
+		if param.PodManagement != "" {
+			errs = append(errs, field.Forbidden(field.NewPath("podManagement"), "only supported by StatefulSets"))
+		}
+		return errs
+	}
+
+	switch param.PodManagement {
+	case "", "OrderedReady", "Parallel":
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("podManagement"), param.PodManagement, []string{"OrderedReady", "Parallel"}))
+	}
+	if param.Service == api.ServiceClusterIP {
+		errs = append(errs, field.Invalid(field.NewPath("service"), param.Service, "StatefulSets require a headless service"))
+	}
+
+	names := make(map[string]bool)
+	paths := make(map[string]bool)
+	for _, vol := range param.Volumes {
+		names[vol.Name] = true
+		paths[vol.MountPath] = true
+	}
+	for i, claim := range param.VolumeClaims {
+		idxPath := field.NewPath("volumeClaims").Index(i)
+		if claim.Name == "" {
+			errs = append(errs, field.Required(idxPath.Child("name"), ""))
+		} else if names[claim.Name] {
+			errs = append(errs, field.Duplicate(idxPath.Child("name"), claim.Name))
+		} else {
+			errs = append(errs, validateDNSLabel(idxPath.Child("name"), claim.Name)...)
+		}
+		names[claim.Name] = true
+		if !strings.HasPrefix(claim.MountPath, "/") {
+			errs = append(errs, field.Invalid(idxPath.Child("mountPath"), claim.MountPath, "must be absolute"))
+		} else if paths[claim.MountPath] {
+			errs = append(errs, field.Duplicate(idxPath.Child("mountPath"), claim.MountPath))
+		}
+		paths[claim.MountPath] = true
+		if _, err := resource.ParseQuantity(claim.Size); err != nil {
+			errs = append(errs, field.Invalid(idxPath.Child("size"), claim.Size, err.Error()))
+		}
+		for j, mode := range claim.AccessModes {
+			switch mode {
+			case "ReadWriteOnce", "ReadOnlyMany", "ReadWriteMany":
+			default:
+				errs = append(errs, field.NotSupported(idxPath.Child("accessModes").Index(j), mode, []string{"ReadWriteOnce", "ReadOnlyMany", "ReadWriteMany"}))
+			}
+		}
+	}
+	return errs
+}
+
+func (c *appCoordinator) generateStatefulSet(param api.RunParam) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"serviceName": param.Name,
+		"selector": map[string]interface{}{
+			"matchLabels": c.generateSelector(param),
+		},
+		"replicas": param.Replicas,
+		"template": c.generatePodTemplate(param),
+	}
+	if param.PodManagement != "" {
+		spec["podManagementPolicy"] = param.PodManagement
+	}
+	if len(param.VolumeClaims) > 0 {
+		spec["volumeClaimTemplates"] = c.generateVolumeClaimTemplates(param)
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "StatefulSet",
+			"metadata":   c.generateObjectMeta(param),
+			"spec":       spec,
+		},
+	}
+}
+
+func (c *appCoordinator) generateVolumeClaimTemplates(param api.RunParam) []interface{} {
+	var result []interface{}
+	for _, claim := range param.VolumeClaims {
+		modes := claim.AccessModes
+		if len(modes) == 0 {
+			modes = []string{"ReadWriteOnce"}
+		}
+		spec := map[string]interface{}{
+			"accessModes": toInterfaceSlice(modes),
+			"resources": map[string]interface{}{
+				"requests": map[string]interface{}{
+					"storage": claim.Size,
+				},
+			},
+		}
+		if claim.StorageClass != "" {
+			spec["storageClassName"] = claim.StorageClass
+		}
+		result = append(result, map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":   claim.Name,
+				"labels": c.generateLabels(param),
+			},
+			"spec": spec,
+		})
+	}
+	return result
+}
+
+// generateVolumeClaimMounts mounts the volume claims into the worker container
+func generateVolumeClaimMounts(claims []api.VolumeClaim) []interface{} {
+	var result []interface{}
+	for _, claim := range claims {
+		result = append(result, map[string]interface{}{
+			"name":      claim.Name,
+			"mountPath": claim.MountPath,
+		})
+	}
+	return result
+}
+
+func (c *appCoordinator) setupStatefulSetInformer() {
+	ctrl := controller.New(c.informerFac, api.StatefulSetsResource)
+	ctrl.SetObjectAddedFunc(func(obj interface{}) {
+		if c.stsEventFunc != nil {
+			uObj, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				log.Println("unexpected type for object")
+				return
+			}
+			c.stsEventFunc(c.newStatefulSetEvent(api.StatefulSetEventNew, uObj))
+		}
+	})
+
+	ctrl.SetObjectUpdatedFunc(func(old, new interface{}) {
+		if c.stsEventFunc != nil {
+			newOne := new.(*unstructured.Unstructured)
+			oldOne := old.(*unstructured.Unstructured)
+			if newOne.GetResourceVersion() != oldOne.GetResourceVersion() {
+				c.stsEventFunc(c.newStatefulSetEvent(api.StatefulSetEventUpdate, newOne))
+			}
+		}
+	})
+
+	ctrl.SetObjectDeletedFunc(func(obj interface{}) {
+		if c.stsEventFunc != nil {
+			uObj, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				log.Println("unexpected type for object")
+				return
+			}
+			c.stsEventFunc(c.newStatefulSetEvent(api.StatefulSetEventDelete, uObj))
+		}
+	})
+}
+
+func (c *appCoordinator) newStatefulSetEvent(eventType api.StatefulSetEventType, obj *unstructured.Unstructured) api.StatefulSetEvent {
+	replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	readyReplicas := getDeploymentReplicasField(obj, "readyReplicas")
+	return api.StatefulSetEvent{
+		Type:            eventType,
+		Name:            obj.GetName(),
+		Namespace:       obj.GetNamespace(),
+		Replicas:        replicas,
+		ReadyReplicas:   readyReplicas,
+		CurrentReplicas: getDeploymentReplicasField(obj, "currentReplicas"),
+		UpdatedReplicas: getDeploymentReplicasField(obj, "updatedReplicas"),
+		Ready:           readyReplicas == replicas,
+		Ordinals:        c.getOrdinals(obj),
+		Source:          obj,
+	}
+}
+
+// getOrdinals lists the ordinal pods of the StatefulSet found in the pod informer cache
+func (c *appCoordinator) getOrdinals(obj *unstructured.Unstructured) []api.OrdinalStatus {
+	lister := c.informerFac.ForResource(api.PodsResource).Lister().ByNamespace(obj.GetNamespace())
+	selector := c.selectorLabels(obj.GetName()).AsSelector()
+	pods, err := lister.List(selector)
+	if err != nil {
+		log.Println("failed to list statefulset pods:", err)
+		return nil
+	}
+
+	var result []api.OrdinalStatus
+	for _, pod := range pods {
+		uPod, ok := pod.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		ordinal, ok := getPodOrdinal(obj.GetName(), uPod.GetName())
+		if !ok {
+			continue
+		}
+		result = append(result, api.OrdinalStatus{Ordinal: ordinal, PodName: uPod.GetName(), Ready: isPodReady(uPod)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Ordinal < result[j].Ordinal })
+	return result
+}
+
+// emitOrdinalEvent reports a change of readiness of a pod owned by a StatefulSet
+func (c *appCoordinator) emitOrdinalEvent(oldPod, newPod *unstructured.Unstructured) {
+	if c.stsEventFunc == nil {
+		return
+	}
+	owner := metav1.GetControllerOf(newPod)
+	if owner == nil || owner.Kind != "StatefulSet" {
+		return
+	}
+	ready := isPodReady(newPod)
+	if ready == isPodReady(oldPod) {
+		return
+	}
+	ordinal, ok := getPodOrdinal(owner.Name, newPod.GetName())
+	if !ok {
+		return
+	}
+
+	lister := c.informerFac.ForResource(api.StatefulSetsResource).Lister().ByNamespace(newPod.GetNamespace())
+	obj, err := lister.Get(owner.Name)
+	if err != nil {
+		log.Println("failed to get statefulset:", err)
+		return
+	}
+	sts, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		log.Println("unexpected type for object")
+		return
+	}
+
+	eventType := api.StatefulSetEventOrdinalNotReady
+	if ready {
+		eventType = api.StatefulSetEventOrdinalReady
+	}
+	e := c.newStatefulSetEvent(eventType, sts)
+	e.Ordinal = api.OrdinalStatus{Ordinal: ordinal, PodName: newPod.GetName(), Ready: ready}
+	c.stsEventFunc(e)
+}
+
+// getPodOrdinal extracts the ordinal from a StatefulSet pod name (<set>-<ordinal>)
+func getPodOrdinal(setName, podName string) (int64, bool) {
+	prefix := setName + "-"
+	if !strings.HasPrefix(podName, prefix) {
+		return 0, false
+	}
+	ordinal, err := strconv.ParseInt(strings.TrimPrefix(podName, prefix), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return ordinal, true
+}
@@ -0,0 +1,172 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestStatefulSetRun(t *testing.T) {
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	param := api.RunParam{
+		Kind:          api.WorkloadStatefulSet,
+		Namespace:     "appns",
+		Name:          "shard",
+		Image:         "image:latest",
+		Replicas:      2,
+		PodManagement: "Parallel",
+		VolumeClaims: []api.VolumeClaim{
+			{Name: "data", MountPath: "/data", Size: "1Gi", StorageClass: "fast"},
+		},
+	}
+	if err := coord.Run(param); err != nil {
+		t.Fatal(err)
+	}
+
+	sts, err := fakeClient.Resource(api.StatefulSetsResource).Namespace("appns").Get("shard", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serviceName, _, _ := unstructured.NestedString(sts.Object, "spec", "serviceName"); serviceName != "shard" {
+		t.Error("unexpected service name:", serviceName)
+	}
+	if policy, _, _ := unstructured.NestedString(sts.Object, "spec", "podManagementPolicy"); policy != "Parallel" {
+		t.Error("unexpected pod management policy:", policy)
+	}
+	claims, _, _ := unstructured.NestedSlice(sts.Object, "spec", "volumeClaimTemplates")
+	if len(claims) != 1 {
+		t.Fatal("unexpected volume claim template count:", len(claims))
+	}
+	claim := claims[0].(map[string]interface{})
+	if storage, _, _ := unstructured.NestedString(claim, "spec", "resources", "requests", "storage"); storage != "1Gi" {
+		t.Error("unexpected claim size:", storage)
+	}
+	if modes, _, _ := unstructured.NestedStringSlice(claim, "spec", "accessModes"); len(modes) != 1 || modes[0] != "ReadWriteOnce" {
+		t.Error("unexpected claim access modes:", modes)
+	}
+	containers, _, _ := unstructured.NestedSlice(sts.Object, "spec", "template", "spec", "containers")
+	mounts, _, _ := unstructured.NestedSlice(containers[0].(map[string]interface{}), "volumeMounts")
+	if len(mounts) != 1 || mounts[0].(map[string]interface{})["mountPath"] != "/data" {
+		t.Error("unexpected volume mounts:", mounts)
+	}
+
+	// governing service
+	svc, err := fakeClient.Resource(api.ServicesResource).Namespace("appns").Get("shard", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clusterIP, _, _ := unstructured.NestedString(svc.Object, "spec", "clusterIP"); clusterIP != "None" {
+		t.Error("expecting headless service, got cluster IP:", clusterIP)
+	}
+
+	// removed by kind
+	if err := coord.Delete(api.DeleteParam{Kind: api.WorkloadStatefulSet, Namespace: "appns", Name: "shard"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatefulSetValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		param api.RunParam
+	}{
+		{
+			name:  "claims on deployment",
+			param: api.RunParam{Name: "app", Image: "image:latest", VolumeClaims: []api.VolumeClaim{{Name: "data", MountPath: "/data", Size: "1Gi"}}},
+		},
+		{
+			name:  "cluster ip service",
+			param: api.RunParam{Kind: api.WorkloadStatefulSet, Name: "app", Image: "image:latest", Port: 8086, Service: api.ServiceClusterIP},
+		},
+		{
+			name:  "bad pod management",
+			param: api.RunParam{Kind: api.WorkloadStatefulSet, Name: "app", Image: "image:latest", PodManagement: "Random"},
+		},
+		{
+			name:  "missing claim size",
+			param: api.RunParam{Kind: api.WorkloadStatefulSet, Name: "app", Image: "image:latest", VolumeClaims: []api.VolumeClaim{{Name: "data", MountPath: "/data"}}},
+		},
+		{
+			name:  "unknown kind",
+			param: api.RunParam{Kind: "ReplicationController", Name: "app", Image: "image:latest"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := assertValidRunParam(test.param); err == nil {
+				t.Error("expecting validation error")
+			}
+		})
+	}
+}
+
+func TestStatefulSetOrdinalEvents(t *testing.T) {
+	timeout := time.Duration(3 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	eventCh := make(chan api.StatefulSetEvent, 10)
+	coord.OnStatefulSetEvent(func(e api.StatefulSetEvent) {
+		eventCh <- e
+	})
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+	if err := coord.Run(api.RunParam{Kind: api.WorkloadStatefulSet, Namespace: "appns", Name: "shard", Image: "image:latest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	podClient := fakeClient.Resource(api.PodsResource).Namespace("appns")
+	pod := generateTestPod("shard-0", "appns", "image:latest")
+	pod.SetLabels(coord.selectorLabels("shard"))
+	pod.SetResourceVersion("1")
+	isController := true
+	pod.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "shard", Controller: &isController},
+	})
+	if _, err := podClient.Create(pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for the pod to be cached, then mark it ready
+	time.Sleep(200 * time.Millisecond)
+	pod.SetResourceVersion("2")
+	unstructured.SetNestedSlice(pod.Object, []interface{}{
+		map[string]interface{}{"type": "Ready", "status": "True"},
+	}, "status", "conditions")
+	if _, err := podClient.Update(pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		select {
+		case e := <-eventCh:
+			if e.Type != api.StatefulSetEventOrdinalReady {
+				continue
+			}
+			if e.Name != "shard" || e.Ordinal.Ordinal != 0 || e.Ordinal.PodName != "shard-0" || !e.Ordinal.Ready {
+				t.Error("unexpected ordinal event:", e.Ordinal)
+			}
+			if len(e.Ordinals) != 1 || !e.Ordinals[0].Ready {
+				t.Error("unexpected ordinals:", e.Ordinals)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("ordinal ready event not received")
+		}
+	}
+}
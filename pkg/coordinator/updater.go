@@ -0,0 +1,233 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// defaultProgressDeadlineSeconds is the progress deadline of the
+// deployments that do not set one, rolloutWaitMargin leaves the
+// deployment controller time to report the deadline as exceeded
+const (
+	defaultProgressDeadlineSeconds = 600
+	rolloutWaitMargin              = 30 * time.Second
+)
+
+func (c *appCoordinator) Update(param api.UpdateParam) error {
+	if err := assertValidUpdateParam(param); err != nil {
+		return err
+	}
+	if param.Container == "" {
+		param.Container = param.Name
+	}
+
+	cl := c.k8sClient.Interface().Resource(api.DeploymentsResource).Namespace(param.Namespace)
+	var updated *unstructured.Unstructured
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := cl.Get(param.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !c.isManaged(existing) {
+			return &api.ConflictError{
+				Kind:        existing.GetKind(),
+				Namespace:   param.Namespace,
+				Name:        param.Name,
+				Coordinator: c.name,
+			}
+		}
+
+		patch, err := generateImagePatch(existing, param)
+		if err != nil {
+			return err
+		}
+		updated, err = cl.Patch(param.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !param.Wait {
+		return nil
+	}
+
+	generation := updated.GetGeneration()
+	rollout, err := c.waitForDeployment(param.Namespace, param.Name, rolloutTimeout(updated, param), func(e api.DeploymentEvent) bool {
+		if e.Type == api.DeploymentEventDelete || e.Generation < generation {
+			return false
+		}
+		switch getDeploymentRolloutEventType(e.Source) {
+		case api.DeploymentEventRolloutComplete, api.DeploymentEventRolloutFailed:
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+
+	if getDeploymentRolloutEventType(rollout.Source) == api.DeploymentEventRolloutFailed {
+		if param.RollbackOnFailure {
+			if err := c.rollback(rollout.Source); err != nil {
+				return fmt.Errorf("rollout of %s/%s failed, rollback failed: %s", param.Namespace, param.Name, err)
+			}
+			return fmt.Errorf("rollout of %s/%s exceeded its progress deadline, rolled back", param.Namespace, param.Name)
+		}
+		return fmt.Errorf("rollout of %s/%s exceeded its progress deadline", param.Namespace, param.Name)
+	}
+	return nil
+}
+
+func assertValidUpdateParam(param api.UpdateParam) error {
+	if param.Name == "" {
+		return errors.New("missing deployment name")
+	}
+	if param.Image == "" {
+		return errors.New("missing deployment image")
+	}
+	if param.ProgressDeadlineSeconds < 0 {
+		return fmt.Errorf("invalid progress deadline %d: must not be negative", param.ProgressDeadlineSeconds)
+	}
+	if param.RollbackOnFailure && !param.Wait {
+		return errors.New("rollback on failure requires waiting for the rollout")
+	}
+	if param.RollbackOnFailure && param.Timeout > 0 && param.ProgressDeadlineSeconds > 0 &&
+		param.Timeout <= time.Duration(param.ProgressDeadlineSeconds)*time.Second {
+		return fmt.Errorf("invalid timeout %s: rollback on failure requires a timeout longer than the progress deadline of %ds", param.Timeout, param.ProgressDeadlineSeconds)
+	}
+	return nil
+}
+
+// rolloutTimeout returns the timeout of param, or by default one
+// longer than the progress deadline of deployment, so that a failed
+// rollout is reported before the wait expires
+func rolloutTimeout(deployment *unstructured.Unstructured, param api.UpdateParam) time.Duration {
+	if param.Timeout > 0 {
+		return param.Timeout
+	}
+	deadline, ok, _ := unstructured.NestedInt64(deployment.Object, "spec", "progressDeadlineSeconds")
+	if !ok || deadline <= 0 {
+		deadline = defaultProgressDeadlineSeconds
+	}
+	return time.Duration(deadline)*time.Second + rolloutWaitMargin
+}
+
+// generateImagePatch returns a merge patch that sets the image of the
+// param container. Merge patches replace lists, so the full container
+// list of existing is sent with the new image.
+func generateImagePatch(existing *unstructured.Unstructured, param api.UpdateParam) ([]byte, error) {
+	containers, _, err := unstructured.NestedSlice(existing.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, container := range containers {
+		containerMap, ok := container.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if containerMap["name"] == param.Container {
+			containerMap["image"] = param.Image
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("container %s not found in deployment %s/%s", param.Container, param.Namespace, param.Name)
+	}
+
+	spec := map[string]interface{}{
+		"template": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": containers,
+			},
+		},
+	}
+	if param.ProgressDeadlineSeconds > 0 {
+		spec["progressDeadlineSeconds"] = param.ProgressDeadlineSeconds
+	}
+
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": existing.GetResourceVersion(),
+		},
+		"spec": spec,
+	}
+	return json.Marshal(patch)
+}
+
+// rollback restores the pod template of the ReplicaSet holding
+// the revision prior to the current revision of the deployment.
+func (c *appCoordinator) rollback(deployment *unstructured.Unstructured) error {
+	current, err := strconv.ParseInt(deployment.GetAnnotations()[revisionAnnotation], 10, 64)
+	if err != nil {
+		return fmt.Errorf("unable to determine current revision: %s", err)
+	}
+
+	cl := c.k8sClient.Interface()
+	rsList, err := cl.Resource(api.ReplicaSetsResource).Namespace(deployment.GetNamespace()).List(metav1.ListOptions{
+		LabelSelector: c.selectorLabels(deployment.GetName()).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	var previous *unstructured.Unstructured
+	var previousRev int64
+	for i := range rsList.Items {
+		rs := &rsList.Items[i]
+		if !isOwnedBy(rs, deployment) {
+			continue
+		}
+		rev, err := strconv.ParseInt(rs.GetAnnotations()[revisionAnnotation], 10, 64)
+		if err != nil {
+			continue
+		}
+		if rev < current && rev > previousRev {
+			previous = rs
+			previousRev = rev
+		}
+	}
+	if previous == nil {
+		return fmt.Errorf("no revision prior to %d found", current)
+	}
+
+	template, _, err := unstructured.NestedMap(previous.Object, "spec", "template")
+	if err != nil {
+		return err
+	}
+	unstructured.RemoveNestedField(template, "metadata", "labels", "pod-template-hash")
+
+	patch, err := json.Marshal([]interface{}{
+		map[string]interface{}{
+			"op":    "replace",
+			"path":  "/spec/template",
+			"value": template,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cl.Resource(api.DeploymentsResource).Namespace(deployment.GetNamespace()).Patch(deployment.GetName(), types.JSONPatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func isOwnedBy(obj, owner *unstructured.Unstructured) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
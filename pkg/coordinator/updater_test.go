@@ -0,0 +1,282 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestUpdater(t *testing.T) {
+	tests := []struct {
+		name          string
+		param         api.UpdateParam
+		status        map[string]interface{}
+		expectedImage string
+		expectedEvent api.DeploymentEventType
+		shouldFail    bool
+	}{
+		{
+			name:  "rollout complete",
+			param: api.UpdateParam{Namespace: "appns", Name: "app-name", Image: "image:v2", Wait: true, Timeout: 2 * time.Second},
+			status: map[string]interface{}{
+				"observedGeneration": int64(1),
+				"replicas":           int64(1),
+				"updatedReplicas":    int64(1),
+				"readyReplicas":      int64(1),
+				"availableReplicas":  int64(1),
+			},
+			expectedImage: "image:v2",
+			expectedEvent: api.DeploymentEventRolloutComplete,
+		},
+		{
+			name:  "rollout failed with rollback",
+			param: api.UpdateParam{Namespace: "appns", Name: "app-name", Image: "image:v2", Wait: true, Timeout: 2 * time.Second, RollbackOnFailure: true},
+			status: map[string]interface{}{
+				"observedGeneration": int64(1),
+				"replicas":           int64(2),
+				"updatedReplicas":    int64(1),
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":   "Progressing",
+						"status": "False",
+						"reason": "ProgressDeadlineExceeded",
+					},
+				},
+			},
+			expectedImage: "image:v1",
+			expectedEvent: api.DeploymentEventRolloutFailed,
+			shouldFail:    true,
+		},
+		{
+			name:       "rollback without wait",
+			param:      api.UpdateParam{Namespace: "appns", Name: "app-name", Image: "image:v2", RollbackOnFailure: true},
+			shouldFail: true,
+		},
+		{
+			name:       "rollback with timeout within the progress deadline",
+			param:      api.UpdateParam{Namespace: "appns", Name: "app-name", Image: "image:v2", Wait: true, Timeout: 30 * time.Second, ProgressDeadlineSeconds: 60, RollbackOnFailure: true},
+			shouldFail: true,
+		},
+		{
+			name:       "unknown container",
+			param:      api.UpdateParam{Namespace: "appns", Name: "app-name", Container: "sidecar", Image: "image:v2"},
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timeout := time.Duration(3 * time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			owner := &appCoordinator{name: "test-coord"}
+			deployment := owner.generateDeployment(api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:v1", Replicas: 1})
+			deployment.SetResourceVersion("1")
+			deployment.SetGeneration(1)
+			deployment.SetUID(types.UID("deployment-uid"))
+			deployment.SetAnnotations(map[string]string{revisionAnnotation: "2"})
+			fakeClient := fake.NewSimpleDynamicClient(
+				runtime.NewScheme(),
+				deployment,
+				generateTestReplicaSet(owner, deployment, "app-name-1", "1", "image:v1"),
+				generateTestReplicaSet(owner, deployment, "app-name-2", "2", "image:v2"),
+			)
+			coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+			coord.name = "test-coord"
+
+			eventCh := make(chan api.DeploymentEventType, 10)
+			coord.OnDeploymentEvent(func(e api.DeploymentEvent) {
+				select {
+				case eventCh <- e.Type:
+				default:
+				}
+			})
+			if err := coord.Start(ctx.Done()); err != nil {
+				t.Fatal(err)
+			}
+
+			// simulate the deployment controller reporting the rollout,
+			// waited for before the test returns
+			statusErr := make(chan error, 1)
+			if test.status != nil {
+				go func() {
+					time.Sleep(200 * time.Millisecond)
+					cl := fakeClient.Resource(api.DeploymentsResource).Namespace("appns")
+					obj, err := cl.Get("app-name", metav1.GetOptions{})
+					if err != nil {
+						statusErr <- err
+						return
+					}
+					obj.SetResourceVersion("2")
+					obj.Object["status"] = test.status
+					_, err = cl.Update(obj, metav1.UpdateOptions{})
+					statusErr <- err
+				}()
+			} else {
+				statusErr <- nil
+			}
+
+			err := coord.Update(test.param)
+			if err := <-statusErr; err != nil {
+				t.Fatal(err)
+			}
+			if test.shouldFail && err == nil {
+				t.Fatal("expecting update to fail")
+			}
+			if !test.shouldFail && err != nil {
+				t.Fatal(err)
+			}
+			if test.expectedImage == "" {
+				return
+			}
+
+			savedObj, err := fakeClient.Resource(api.DeploymentsResource).Namespace("appns").Get("app-name", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			containers, _, _ := unstructured.NestedSlice(savedObj.Object, "spec", "template", "spec", "containers")
+			if image := containers[0].(map[string]interface{})["image"]; image != test.expectedImage {
+				t.Error("unexpected image:", image)
+			}
+
+			for {
+				select {
+				case eType := <-eventCh:
+					if eType == test.expectedEvent {
+						return
+					}
+				case <-ctx.Done():
+					t.Fatal("did not receive rollout event", test.expectedEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestRolloutEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	owner := &appCoordinator{name: "test-coord"}
+	deployment := owner.generateDeployment(api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:v1", Replicas: 2})
+	deployment.SetResourceVersion("1")
+	deployment.SetGeneration(2)
+	deployment.Object["status"] = map[string]interface{}{"observedGeneration": int64(1)}
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme(), deployment)
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	eventCh := make(chan api.DeploymentEventType, 10)
+	coord.OnDeploymentEvent(func(e api.DeploymentEvent) {
+		switch e.Type {
+		case api.DeploymentEventRolloutProgressing, api.DeploymentEventRolloutComplete, api.DeploymentEventRolloutFailed:
+			eventCh <- e.Type
+		}
+	})
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+
+	cl := fakeClient.Resource(api.DeploymentsResource).Namespace("appns")
+	update := func(version string, status map[string]interface{}) {
+		obj, err := cl.Get("app-name", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj.SetResourceVersion(version)
+		obj.Object["status"] = status
+		if _, err := cl.Update(obj, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the rollout state and observed generation are unchanged
+	update("2", map[string]interface{}{"observedGeneration": int64(1), "replicas": int64(2)})
+	// the new generation is observed
+	update("3", map[string]interface{}{"observedGeneration": int64(2), "replicas": int64(2), "updatedReplicas": int64(1)})
+	// the rollout completes
+	update("4", map[string]interface{}{"observedGeneration": int64(2), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2)})
+
+	expected := []api.DeploymentEventType{api.DeploymentEventRolloutProgressing, api.DeploymentEventRolloutComplete}
+	for _, eType := range expected {
+		select {
+		case e := <-eventCh:
+			if e != eType {
+				t.Fatalf("unexpected rollout event %d, expecting %d", e, eType)
+			}
+		case <-ctx.Done():
+			t.Fatal("did not receive rollout event", eType)
+		}
+	}
+	select {
+	case e := <-eventCh:
+		t.Error("unexpected rollout event:", e)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func generateTestReplicaSet(c *appCoordinator, deployment *unstructured.Unstructured, name, revision, image string) *unstructured.Unstructured {
+	lbls := make(map[string]interface{})
+	for k, v := range c.selectorLabels(deployment.GetName()) {
+		lbls[k] = v
+	}
+	lbls["pod-template-hash"] = name
+	rs := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "ReplicaSet",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": deployment.GetNamespace(),
+				"labels":    lbls,
+				"annotations": map[string]interface{}{
+					revisionAnnotation: revision,
+				},
+			},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"labels": lbls,
+					},
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name":  deployment.GetName(),
+								"image": image,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	rs.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: deployment.GetName(), UID: deployment.GetUID()},
+	})
+	return rs
+}
+
+func TestRolloutTimeout(t *testing.T) {
+	owner := &appCoordinator{name: "test-coord"}
+	deployment := owner.generateDeployment(api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:v1", Replicas: 1})
+
+	if timeout := rolloutTimeout(deployment, api.UpdateParam{}); timeout != 600*time.Second+rolloutWaitMargin {
+		t.Error("unexpected default timeout:", timeout)
+	}
+	unstructured.SetNestedField(deployment.Object, int64(60), "spec", "progressDeadlineSeconds")
+	if timeout := rolloutTimeout(deployment, api.UpdateParam{}); timeout != 60*time.Second+rolloutWaitMargin {
+		t.Error("unexpected timeout for the deployment deadline:", timeout)
+	}
+	if timeout := rolloutTimeout(deployment, api.UpdateParam{Timeout: time.Minute}); timeout != time.Minute {
+		t.Error("unexpected timeout:", timeout)
+	}
+}
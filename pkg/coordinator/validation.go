@@ -0,0 +1,299 @@
+package coordinator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// imageRefRegexp matches image references such as
+// registry:5000/org/name:tag@sha256:<digest>, following
+// the grammar of the docker distribution reference package
+var imageRefRegexp = func() *regexp.Regexp {
+	const (
+		domainComponent = `(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])`
+		domain          = domainComponent + `(?:\.` + domainComponent + `)*(?::[0-9]+)?`
+		nameComponent   = `[a-z0-9]+(?:(?:[._]|__|[-]*)[a-z0-9]+)*`
+		name            = `(?:` + domain + `/)?` + nameComponent + `(?:/` + nameComponent + `)*`
+		tag             = `[\w][\w.-]{0,127}`
+		digest          = `[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}`
+	)
+	return regexp.MustCompile(`^` + name + `(?::` + tag + `)?(?:@` + digest + `)?$`)
+}()
+
+// cronJobNameMaxLength leaves room for the suffix
+// appended to the names of the scheduled Jobs
+const cronJobNameMaxLength = 52
+
+// assertValidRunParam returns an *api.ValidationError
+// listing every invalid field of param
+func assertValidRunParam(param api.RunParam) error {
+	if errs := validateRunParam(param); len(errs) > 0 {
+		return &api.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func validateRunParam(param api.RunParam) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validateName(param)...)
+	if param.Namespace != "" {
+		errs = append(errs, validateDNSLabel(field.NewPath("namespace"), param.Namespace)...)
+	}
+	if _, err := workloadResource(param.Kind); err != nil {
+		kinds := []string{
+			string(api.WorkloadDeployment),
+			string(api.WorkloadStatefulSet),
+			string(api.WorkloadDaemonSet),
+			string(api.WorkloadJob),
+			string(api.WorkloadCronJob),
+		}
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), param.Kind, kinds))
+	}
+	errs = append(errs, validateImage(field.NewPath("image"), param.Image)...)
+	errs = append(errs, validatePullPolicy(field.NewPath("imagePullPolicy"), param.ImagePullPolicy)...)
+	errs = append(errs, validateWorkingDir(field.NewPath("workingDir"), param.WorkingDir)...)
+	errs = append(errs, validatePort(field.NewPath("port"), param.Port)...)
+	if param.Replicas < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("replicas"), param.Replicas, "must not be negative"))
+	}
+	errs = append(errs, validateEnvs(field.NewPath("envs"), param.Envs)...)
+	errs = append(errs, validateLabels(field.NewPath("labels"), param.Labels)...)
+	errs = append(errs, validateResources(param.Requests, param.Limits)...)
+	errs = append(errs, validateProbe(field.NewPath("livenessProbe"), "liveness", param.LivenessProbe, param.Port)...)
+	errs = append(errs, validateProbe(field.NewPath("readinessProbe"), "readiness", param.ReadinessProbe, param.Port)...)
+	errs = append(errs, validateProbe(field.NewPath("startupProbe"), "startup", param.StartupProbe, param.Port)...)
+	if param.HTTPReadiness && param.ReadinessProbe == nil && param.Port == 0 {
+		errs = append(errs, field.Required(field.NewPath("port"), "HTTP readiness requires a port"))
+	}
+	errs = append(errs, validateVolumes(field.NewPath("volumes"), param.Volumes)...)
+	errs = append(errs, validateScheduling(param)...)
+	errs = append(errs, validateContainers(param)...)
+	errs = append(errs, validateService(param)...)
+	errs = append(errs, validatePermissions(param)...)
+	errs = append(errs, validateSecurityContext(param)...)
+	errs = append(errs, validateStatefulSet(param)...)
+	errs = append(errs, validateDaemonSet(param)...)
+	errs = append(errs, validateJob(param)...)
+	errs = append(errs, validateCronJob(param)...)
+	return errs
+}
+
+// validateName checks that the workload name can also
+// name the worker container and the companion Service
+func validateName(param api.RunParam) field.ErrorList {
+	fldPath := field.NewPath("name")
+	if param.Name == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	var errs field.ErrorList
+	// StatefulSets default to a headless Service, see withRunDefaults
+	if param.Service != api.ServiceNone || param.Kind == api.WorkloadStatefulSet {
+		// Service names must also start with a letter
+		for _, msg := range validation.IsDNS1035Label(param.Name) {
+			errs = append(errs, field.Invalid(fldPath, param.Name, msg))
+		}
+	} else {
+		errs = validateDNSLabel(fldPath, param.Name)
+	}
+	if param.Kind == api.WorkloadCronJob && len(param.Name) > cronJobNameMaxLength {
+		errs = append(errs, field.TooLong(fldPath, param.Name, cronJobNameMaxLength))
+	}
+	return errs
+}
+
+func validateDNSLabel(fldPath *field.Path, value string) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Label(value) {
+		errs = append(errs, field.Invalid(fldPath, value, msg))
+	}
+	return errs
+}
+
+func validateImage(fldPath *field.Path, image string) field.ErrorList {
+	if image == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	if !imageRefRegexp.MatchString(image) {
+		return field.ErrorList{field.Invalid(fldPath, image, "must be a valid image reference, e.g. registry/name:tag")}
+	}
+	return nil
+}
+
+func validatePort(fldPath *field.Path, port int64) field.ErrorList {
+	if port == 0 {
+		return nil
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsValidPortNum(int(port)) {
+		errs = append(errs, field.Invalid(fldPath, port, msg))
+	}
+	return errs
+}
+
+func validateWorkingDir(fldPath *field.Path, dir string) field.ErrorList {
+	if dir != "" && !strings.HasPrefix(dir, "/") {
+		return field.ErrorList{field.Invalid(fldPath, dir, "must be absolute")}
+	}
+	return nil
+}
+
+func validatePullPolicy(fldPath *field.Path, policy string) field.ErrorList {
+	switch policy {
+	case "", "Always", "Never", "IfNotPresent":
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(fldPath, policy, []string{"Always", "Never", "IfNotPresent"})}
+}
+
+func validateEnvs(fldPath *field.Path, envs []string) field.ErrorList {
+	var errs field.ErrorList
+	for i, env := range envs {
+		if _, err := parseEnv(env); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Index(i), env, err.Error()))
+		}
+	}
+	return errs
+}
+
+func validateLabels(fldPath *field.Path, selector string) field.ErrorList {
+	if _, err := parseLabels(selector); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, selector, err.Error())}
+	}
+	return nil
+}
+
+func validateResources(requests, limits api.Resources) field.ErrorList {
+	reqs, errs := validateResourceList(field.NewPath("requests"), requests)
+	lims, limErrs := validateResourceList(field.NewPath("limits"), limits)
+	errs = append(errs, limErrs...)
+	if name, ok := exceededLimit(reqs, lims); ok {
+		req, lim := reqs[name], lims[name]
+		errs = append(errs, field.Invalid(field.NewPath("requests").Child(name), req.String(), fmt.Sprintf("must not exceed limit %s", lim.String())))
+	}
+	return errs
+}
+
+func validateResourceList(fldPath *field.Path, res api.Resources) (map[string]resource.Quantity, field.ErrorList) {
+	var errs field.ErrorList
+	result := make(map[string]resource.Quantity)
+	for _, entry := range resourceValues(res) {
+		if entry.value == "" {
+			continue
+		}
+		q, err := parseQuantity(entry.value)
+		if err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child(entry.name), entry.value, err.Error()))
+			continue
+		}
+		result[entry.name] = q
+	}
+	return result, errs
+}
+
+func validateProbe(fldPath *field.Path, kind string, probe *api.Probe, defaultPort int64) field.ErrorList {
+	if probe == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	handlers := 0
+	if probe.HTTP != nil {
+		handlers++
+		switch probe.HTTP.Scheme {
+		case "", "HTTP", "HTTPS":
+		default:
+			errs = append(errs, field.NotSupported(fldPath.Child("http", "scheme"), probe.HTTP.Scheme, []string{"HTTP", "HTTPS"}))
+		}
+		if probe.HTTP.Port == 0 && defaultPort == 0 {
+			errs = append(errs, field.Required(fldPath.Child("http", "port"), "no default port to probe"))
+		}
+		errs = append(errs, validatePort(fldPath.Child("http", "port"), probe.HTTP.Port)...)
+	}
+	if probe.TCP != nil {
+		handlers++
+		if probe.TCP.Port == 0 && defaultPort == 0 {
+			errs = append(errs, field.Required(fldPath.Child("tcp", "port"), "no default port to probe"))
+		}
+		errs = append(errs, validatePort(fldPath.Child("tcp", "port"), probe.TCP.Port)...)
+	}
+	if len(probe.Exec) > 0 {
+		handlers++
+	}
+	if handlers != 1 {
+		errs = append(errs, field.Invalid(fldPath, kind, "exactly one of HTTP, TCP or Exec must be set"))
+	}
+	for _, entry := range []struct {
+		name string
+		val  int64
+	}{
+		{"initialDelaySeconds", probe.InitialDelaySeconds},
+		{"periodSeconds", probe.PeriodSeconds},
+		{"timeoutSeconds", probe.TimeoutSeconds},
+		{"successThreshold", probe.SuccessThreshold},
+		{"failureThreshold", probe.FailureThreshold},
+	} {
+		if entry.val < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child(entry.name), entry.val, "must not be negative"))
+		}
+	}
+	if kind != "readiness" && probe.SuccessThreshold > 1 {
+		errs = append(errs, field.Invalid(fldPath.Child("successThreshold"), probe.SuccessThreshold, fmt.Sprintf("must be 1 for %s probes", kind)))
+	}
+	return errs
+}
+
+func validateVolumes(fldPath *field.Path, volumes []api.Volume) field.ErrorList {
+	var errs field.ErrorList
+	names := make(map[string]bool)
+	paths := make(map[string]bool)
+	for i, vol := range volumes {
+		idxPath := fldPath.Index(i)
+		if vol.Name == "" {
+			errs = append(errs, field.Required(idxPath.Child("name"), ""))
+		} else if names[vol.Name] {
+			errs = append(errs, field.Duplicate(idxPath.Child("name"), vol.Name))
+		} else {
+			errs = append(errs, validateDNSLabel(idxPath.Child("name"), vol.Name)...)
+		}
+		names[vol.Name] = true
+
+		if !strings.HasPrefix(vol.MountPath, "/") {
+			errs = append(errs, field.Invalid(idxPath.Child("mountPath"), vol.MountPath, "must be absolute"))
+		} else if paths[vol.MountPath] {
+			errs = append(errs, field.Duplicate(idxPath.Child("mountPath"), vol.MountPath))
+		}
+		paths[vol.MountPath] = true
+		if strings.HasPrefix(vol.SubPath, "/") || strings.Contains(vol.SubPath, "..") {
+			errs = append(errs, field.Invalid(idxPath.Child("subPath"), vol.SubPath, "must be relative"))
+		}
+
+		sources := 0
+		for _, src := range []string{vol.ConfigMap, vol.Secret, vol.PersistentVolumeClaim} {
+			if src != "" {
+				sources++
+			}
+		}
+		if vol.EmptyDir != nil {
+			sources++
+			switch vol.EmptyDir.Medium {
+			case "", "Memory":
+			default:
+				errs = append(errs, field.NotSupported(idxPath.Child("emptyDir", "medium"), vol.EmptyDir.Medium, []string{"", "Memory"}))
+			}
+			if vol.EmptyDir.SizeLimit != "" {
+				if _, err := resource.ParseQuantity(vol.EmptyDir.SizeLimit); err != nil {
+					errs = append(errs, field.Invalid(idxPath.Child("emptyDir", "sizeLimit"), vol.EmptyDir.SizeLimit, err.Error()))
+				}
+			}
+		}
+		if sources != 1 {
+			errs = append(errs, field.Invalid(idxPath, vol.Name, "exactly one of ConfigMap, Secret, PersistentVolumeClaim or EmptyDir must be set"))
+		}
+	}
+	return errs
+}
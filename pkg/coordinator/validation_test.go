@@ -0,0 +1,122 @@
+package coordinator
+
+import (
+	"testing"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateRunParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		param    api.RunParam
+		expected map[string]field.ErrorType
+	}{
+		{
+			name:  "valid",
+			param: api.RunParam{Namespace: "appns", Name: "app", Image: "registry.local:5000/org/app:1.0", Port: 8080, Envs: []string{"LOG_LEVEL=debug"}},
+		},
+		{
+			name:  "image digest",
+			param: api.RunParam{Name: "app", Image: "app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+		},
+		{
+			name:     "missing name and image",
+			param:    api.RunParam{},
+			expected: map[string]field.ErrorType{"name": field.ErrorTypeRequired, "image": field.ErrorTypeRequired},
+		},
+		{
+			name:  "malformed fields",
+			param: api.RunParam{Namespace: "App_NS", Name: "App", Image: "Registry/App:latest", Port: 70000, Replicas: -1},
+			expected: map[string]field.ErrorType{
+				"namespace": field.ErrorTypeInvalid,
+				"name":      field.ErrorTypeInvalid,
+				"image":     field.ErrorTypeInvalid,
+				"port":      field.ErrorTypeInvalid,
+				"replicas":  field.ErrorTypeInvalid,
+			},
+		},
+		{
+			name:     "service name starting with digit",
+			param:    api.RunParam{Name: "1app", Image: "app", Port: 8080, Service: api.ServiceClusterIP},
+			expected: map[string]field.ErrorType{"name": field.ErrorTypeInvalid},
+		},
+		{
+			name:     "statefulset name starting with digit",
+			param:    api.RunParam{Kind: api.WorkloadStatefulSet, Name: "0shard", Image: "app"},
+			expected: map[string]field.ErrorType{"name": field.ErrorTypeInvalid},
+		},
+		{
+			name:     "job replicas",
+			param:    api.RunParam{Kind: api.WorkloadJob, Name: "batch", Image: "app", Replicas: 2},
+			expected: map[string]field.ErrorType{"replicas": field.ErrorTypeForbidden},
+		},
+		{
+			name:     "cronjob replicas",
+			param:    api.RunParam{Kind: api.WorkloadCronJob, Name: "batch", Image: "app", Schedule: "*/5 * * * *", Replicas: 2},
+			expected: map[string]field.ErrorType{"replicas": field.ErrorTypeForbidden},
+		},
+		{
+			name:     "env var name",
+			param:    api.RunParam{Name: "app", Image: "app", Envs: []string{"OK=1", "1BAD=2"}},
+			expected: map[string]field.ErrorType{"envs[1]": field.ErrorTypeInvalid},
+		},
+		{
+			name:     "label value",
+			param:    api.RunParam{Name: "app", Image: "app", Labels: "tier=back end"},
+			expected: map[string]field.ErrorType{"labels": field.ErrorTypeInvalid},
+		},
+		{
+			name:     "unknown kind",
+			param:    api.RunParam{Kind: "ReplicaSet", Name: "app", Image: "app"},
+			expected: map[string]field.ErrorType{"kind": field.ErrorTypeNotSupported},
+		},
+		{
+			name: "nested fields",
+			param: api.RunParam{
+				Name:     "app",
+				Image:    "app",
+				Requests: api.Resources{CPU: "2"},
+				Limits:   api.Resources{CPU: "1"},
+				Volumes:  []api.Volume{{Name: "data", MountPath: "data", EmptyDir: &api.EmptyDir{}}},
+				Sidecars: []api.Container{{Name: "shipper", Image: "shipper", Ports: []api.ContainerPort{{Port: 9090, Protocol: "ICMP"}}}},
+			},
+			expected: map[string]field.ErrorType{
+				"requests.cpu":                  field.ErrorTypeInvalid,
+				"volumes[0].mountPath":          field.ErrorTypeInvalid,
+				"sidecars[0].ports[0].protocol": field.ErrorTypeNotSupported,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := assertValidRunParam(test.param)
+			if len(test.expected) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			verr, ok := err.(*api.ValidationError)
+			if !ok {
+				t.Fatalf("expecting *api.ValidationError, got %T: %v", err, err)
+			}
+			if len(verr.Errors) != len(test.expected) {
+				t.Fatalf("expecting %d errors, got %d: %v", len(test.expected), len(verr.Errors), err)
+			}
+			for _, fieldErr := range verr.Errors {
+				errType, ok := test.expected[fieldErr.Field]
+				if !ok {
+					t.Errorf("unexpected error: %v", fieldErr)
+					continue
+				}
+				if fieldErr.Type != errType {
+					t.Errorf("expecting %s for %s, got %s", errType, fieldErr.Field, fieldErr.Type)
+				}
+			}
+		})
+	}
+}
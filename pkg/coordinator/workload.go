@@ -0,0 +1,353 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/controller"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// workloadEventBuffer is the capacity of the Workload.Events channel
+const workloadEventBuffer = 64
+
+// objectListener receives the changes of the workloads and pods
+// seen by the informers, with the resource they belong to
+type objectListener func(res schema.GroupVersionResource, eventType api.WorkloadEventType, obj *unstructured.Unstructured)
+
+// RunWorkload runs param like Run and returns a handle on the workload.
+// The coordinator must be started for the handle to report its state.
+func (c *appCoordinator) RunWorkload(param api.RunParam) (api.Workload, error) {
+	// an unsupported kind fails the validation of run
+	defaulted := c.withRunDefaults(param)
+	res, _ := workloadResource(defaulted.Kind)
+
+	// listen before running so that no event is missed
+	w := &workload{
+		coord:     c,
+		kind:      defaulted.Kind,
+		res:       res,
+		namespace: defaulted.Namespace,
+		name:      param.Name,
+		selector:  c.selectorLabels(param.Name).AsSelector(),
+		events:    make(chan api.WorkloadEvent, workloadEventBuffer),
+		pending:   make(map[string]api.WorkloadEvent),
+		notify:    make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	go w.forward()
+	w.remove = c.addObjectListener(w.dispatch)
+	if _, err := c.run(param, false); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// setupObjectWatch feeds the object listeners from the informer
+// of res, a workload or pod resource
+func (c *appCoordinator) setupObjectWatch(res schema.GroupVersionResource) {
+	ctrl := controller.New(c.informerFac, res)
+	ctrl.SetObjectAddedFunc(func(obj interface{}) {
+		uObj, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			log.Println("unexpected type for object")
+			return
+		}
+		c.emitObjectEvent(res, api.WorkloadEventNew, uObj)
+	})
+	ctrl.SetObjectUpdatedFunc(func(old, new interface{}) {
+		newOne := new.(*unstructured.Unstructured)
+		oldOne := old.(*unstructured.Unstructured)
+		if newOne.GetResourceVersion() != oldOne.GetResourceVersion() {
+			c.emitObjectEvent(res, api.WorkloadEventUpdate, newOne)
+		}
+	})
+	ctrl.SetObjectDeletedFunc(func(obj interface{}) {
+		uObj, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			log.Println("unexpected type for object")
+			return
+		}
+		c.emitObjectEvent(res, api.WorkloadEventDelete, uObj)
+	})
+}
+
+func (c *appCoordinator) emitObjectEvent(res schema.GroupVersionResource, eventType api.WorkloadEventType, obj *unstructured.Unstructured) {
+	c.listenerMu.RLock()
+	defer c.listenerMu.RUnlock()
+	for _, fn := range c.objectListeners {
+		fn(res, eventType, obj)
+	}
+}
+
+// addObjectListener registers fn to receive the changes of
+// workloads and pods. The returned func removes fn.
+func (c *appCoordinator) addObjectListener(fn objectListener) func() {
+	c.listenerMu.Lock()
+	defer c.listenerMu.Unlock()
+	id := c.nextListenerID
+	c.nextListenerID++
+	c.objectListeners[id] = fn
+	return func() {
+		c.listenerMu.Lock()
+		defer c.listenerMu.Unlock()
+		delete(c.objectListeners, id)
+	}
+}
+
+type workload struct {
+	coord     *appCoordinator
+	kind      api.WorkloadKind
+	res       schema.GroupVersionResource
+	namespace string
+	name      string
+	selector  labels.Selector
+	events    chan api.WorkloadEvent
+
+	// the events not yet sent, the latest one of each object
+	// keyed by resource and name, in the order of their objects
+	mu      sync.Mutex
+	pending map[string]api.WorkloadEvent
+	queue   []string
+	notify  chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+	remove    func()
+}
+
+func (w *workload) Kind() api.WorkloadKind {
+	return w.kind
+}
+
+func (w *workload) Namespace() string {
+	return w.namespace
+}
+
+func (w *workload) Name() string {
+	return w.name
+}
+
+func (w *workload) Events() <-chan api.WorkloadEvent {
+	return w.events
+}
+
+func (w *workload) Close() {
+	w.closeOnce.Do(func() {
+		// forward closes the events channel
+		w.remove()
+		close(w.done)
+	})
+}
+
+// owns returns true if obj is the workload or one of its pods
+func (w *workload) owns(res schema.GroupVersionResource, obj *unstructured.Unstructured) bool {
+	if obj.GetNamespace() != w.namespace {
+		return false
+	}
+	if res == api.PodsResource {
+		return w.selector.Matches(labels.Set(obj.GetLabels()))
+	}
+	return res == w.res && obj.GetName() == w.name
+}
+
+func (w *workload) dispatch(res schema.GroupVersionResource, eventType api.WorkloadEventType, obj *unstructured.Unstructured) {
+	if !w.owns(res, obj) {
+		return
+	}
+	e := api.WorkloadEvent{
+		Type:      eventType,
+		Kind:      w.kind,
+		Name:      w.name,
+		Namespace: w.namespace,
+		Source:    obj,
+	}
+	if res == api.PodsResource {
+		pod := newWorkloadPod(obj)
+		e.Pod = &pod
+		switch eventType {
+		case api.WorkloadEventNew:
+			e.Type = api.WorkloadEventPodNew
+		case api.WorkloadEventUpdate:
+			e.Type = api.WorkloadEventPodUpdate
+		case api.WorkloadEventDelete:
+			e.Type = api.WorkloadEventPodDelete
+		}
+	} else {
+		e.Status = getWorkloadStatus(w.kind, obj)
+	}
+
+	// replace the pending event of the object, if any,
+	// so that the informers never wait for the consumer
+	key := res.Resource + "/" + obj.GetName()
+	w.mu.Lock()
+	if _, ok := w.pending[key]; !ok {
+		w.queue = append(w.queue, key)
+	}
+	w.pending[key] = e
+	w.mu.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// forward sends the pending events until the workload is closed
+func (w *workload) forward() {
+	defer close(w.events)
+	for {
+		select {
+		case <-w.notify:
+		case <-w.done:
+			return
+		}
+		for {
+			w.mu.Lock()
+			if len(w.queue) == 0 {
+				w.mu.Unlock()
+				break
+			}
+			key := w.queue[0]
+			w.queue = w.queue[1:]
+			e := w.pending[key]
+			delete(w.pending, key)
+			w.mu.Unlock()
+
+			select {
+			case w.events <- e:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+func (w *workload) get() (*unstructured.Unstructured, error) {
+	obj, err := w.coord.informerFac.ForResource(w.res).Lister().ByNamespace(w.namespace).Get(w.name)
+	if err != nil {
+		return nil, err
+	}
+	uObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T for %s %s/%s", obj, w.kind, w.namespace, w.name)
+	}
+	return uObj, nil
+}
+
+func (w *workload) Status() (api.WorkloadStatus, error) {
+	obj, err := w.get()
+	if err != nil {
+		return api.WorkloadStatus{}, err
+	}
+	return getWorkloadStatus(w.kind, obj), nil
+}
+
+func (w *workload) Pods() ([]api.WorkloadPod, error) {
+	objs, err := w.coord.informerFac.ForResource(api.PodsResource).Lister().ByNamespace(w.namespace).List(w.selector)
+	if err != nil {
+		return nil, err
+	}
+	var pods []api.WorkloadPod
+	for _, obj := range objs {
+		if uObj, ok := obj.(*unstructured.Unstructured); ok {
+			pods = append(pods, newWorkloadPod(uObj))
+		}
+	}
+	return pods, nil
+}
+
+// WaitReady checks the workload each time it changes, starting
+// with its cached state. A workload not yet seen by the informers
+// is not ready.
+func (w *workload) WaitReady(ctx context.Context) error {
+	changed := make(chan struct{}, 1)
+	remove := w.coord.addObjectListener(func(res schema.GroupVersionResource, _ api.WorkloadEventType, obj *unstructured.Unstructured) {
+		if res == w.res && w.owns(res, obj) {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer remove()
+
+	for {
+		obj, err := w.get()
+		switch {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			return err
+		case w.kind == api.WorkloadJob && getJobState(obj) == api.JobEventFailed:
+			e := newJobEvent(api.JobEventFailed, obj)
+			return fmt.Errorf("job %s/%s failed: %s", w.namespace, w.name, e.Message)
+		case getWorkloadStatus(w.kind, obj).Ready:
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("%s %s/%s not ready: %s", w.kind, w.namespace, w.name, ctx.Err())
+		}
+	}
+}
+
+// getWorkloadStatus returns the replica counts of obj, a workload of kind
+func getWorkloadStatus(kind api.WorkloadKind, obj *unstructured.Unstructured) api.WorkloadStatus {
+	status := api.WorkloadStatus{ServiceDNS: obj.GetAnnotations()[serviceAnnotation]}
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	current := observed >= obj.GetGeneration()
+
+	switch kind {
+	case api.WorkloadDaemonSet:
+		status.DesiredReplicas = getDeploymentReplicasField(obj, "desiredNumberScheduled")
+		status.ReadyReplicas = getDeploymentReplicasField(obj, "numberReady")
+		status.Ready = current && isDaemonSetReady(obj)
+	case api.WorkloadJob:
+		status.DesiredReplicas = 1
+		if completions, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "completions"); ok {
+			status.DesiredReplicas = completions
+		}
+		status.ReadyReplicas = getDeploymentReplicasField(obj, "succeeded")
+		status.Ready = getJobState(obj) == api.JobEventSucceeded
+	case api.WorkloadCronJob:
+		active, _, _ := unstructured.NestedSlice(obj.Object, "status", "active")
+		status.ReadyReplicas = int64(len(active))
+		status.Ready = true
+	default:
+		status.DesiredReplicas = 1
+		if replicas, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas"); ok {
+			status.DesiredReplicas = replicas
+		}
+		status.ReadyReplicas = getDeploymentReplicasField(obj, "readyReplicas")
+		status.Ready = current && status.ReadyReplicas >= status.DesiredReplicas
+		if kind == api.WorkloadDeployment {
+			// old replicas still count as ready during a rollout
+			status.Ready = status.Ready && getDeploymentReplicasField(obj, "updatedReplicas") >= status.DesiredReplicas
+		}
+	}
+	return status
+}
+
+func newWorkloadPod(obj *unstructured.Unstructured) api.WorkloadPod {
+	hostIP, _, _ := unstructured.NestedString(obj.Object, "status", "hostIP")
+	podIP, _, _ := unstructured.NestedString(obj.Object, "status", "podIP")
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	return api.WorkloadPod{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		HostIP:    hostIP,
+		PodIP:     podIP,
+		Running:   phase == "Running",
+		Ready:     isPodReady(obj),
+		Ports:     getPodPorts(obj),
+	}
+}
@@ -0,0 +1,202 @@
+package coordinator
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	"github.com/vladimirvivien/horizon/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+// nextEvent returns the next event of type eventType from w
+func nextEvent(t *testing.T, w api.Workload, eventType api.WorkloadEventType) api.WorkloadEvent {
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case e := <-w.Events():
+			if e.Type == eventType {
+				return e
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for workload event", eventType)
+			return api.WorkloadEvent{}
+		}
+	}
+}
+
+func TestWorkload(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := coord.RunWorkload(api.RunParam{
+		Namespace: "appns",
+		Name:      "app-name",
+		Image:     "image:latest",
+		Replicas:  2,
+		Port:      8086,
+		Service:   api.ServiceClusterIP,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	e := nextEvent(t, w, api.WorkloadEventNew)
+	if e.Kind != api.WorkloadDeployment || e.Status.DesiredReplicas != 2 || e.Status.Ready {
+		t.Errorf("unexpected new event: %#v", e)
+	}
+
+	// not ready until the replicas are
+	waitCtx, waitCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer waitCancel()
+	if err := w.WaitReady(waitCtx); err == nil {
+		t.Fatal("expecting workload not to be ready")
+	}
+
+	// pods of the workload are reported
+	pod := generateTestPod("app-name-1", "appns", "image:latest")
+	pod.SetLabels(map[string]string{"app": "app-name", "coordinated": "true", "coordinator": "test-coord"})
+	if _, err := fakeClient.Resource(api.PodsResource).Namespace("appns").Create(pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	other := generateTestPod("other-1", "appns", "image:latest")
+	other.SetLabels(map[string]string{"app": "other", "coordinated": "true", "coordinator": "test-coord"})
+	if _, err := fakeClient.Resource(api.PodsResource).Namespace("appns").Create(other, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	e = nextEvent(t, w, api.WorkloadEventPodNew)
+	if e.Pod == nil || e.Pod.Name != "app-name-1" {
+		t.Fatalf("unexpected pod event: %#v", e)
+	}
+	pods, err := w.Pods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 || pods[0].PodIP != "172.17.0.8" || !pods[0].Running {
+		t.Errorf("unexpected pods: %#v", pods)
+	}
+
+	// ready once the replicas are
+	readyCh := make(chan error, 1)
+	go func() { readyCh <- w.WaitReady(ctx) }()
+
+	deploy, err := fakeClient.Resource(api.DeploymentsResource).Namespace("appns").Get("app-name", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unstructured.SetNestedField(deploy.Object, int64(2), "status", "readyReplicas")
+	unstructured.SetNestedField(deploy.Object, int64(2), "status", "updatedReplicas")
+	deploy.SetResourceVersion("2")
+	if _, err := fakeClient.Resource(api.DeploymentsResource).Namespace("appns").Update(deploy, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-readyCh; err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := w.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Ready || status.ReadyReplicas != 2 || status.DesiredReplicas != 2 || status.ServiceDNS != "app-name.appns.svc" {
+		t.Errorf("unexpected status: %#v", status)
+	}
+
+	w.Close()
+	for range w.Events() {
+		// drain until closed
+	}
+}
+
+func TestWorkload_JobFailed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+	if err := coord.Start(ctx.Done()); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := coord.RunWorkload(api.RunParam{Kind: api.WorkloadJob, Namespace: "appns", Name: "batch", Image: "image:latest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	job, err := fakeClient.Resource(api.JobsResource).Namespace("appns").Get("batch", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unstructured.SetNestedSlice(job.Object, []interface{}{
+		map[string]interface{}{"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded", "message": "Job has reached the specified backoff limit"},
+	}, "status", "conditions")
+	job.SetResourceVersion("2")
+	if _, err := fakeClient.Resource(api.JobsResource).Namespace("appns").Update(job, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.WaitReady(ctx); err == nil || ctx.Err() != nil {
+		t.Fatal("expecting job failure, got:", err)
+	}
+}
+
+func TestWorkloadEvents_Coalesced(t *testing.T) {
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	coord := newCoord(client.NewFromDynamicClient("", fakeClient))
+	coord.name = "test-coord"
+
+	w, err := coord.RunWorkload(api.RunParam{Namespace: "appns", Name: "app-name", Image: "image:latest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := fakeClient.Resource(api.DeploymentsResource).Namespace("appns").Get("app-name", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// events beyond the channel capacity neither block nor get lost
+	count := 3 * workloadEventBuffer
+	for i := 1; i <= count; i++ {
+		obj := obj.DeepCopy()
+		obj.SetResourceVersion(strconv.Itoa(i))
+		coord.emitObjectEvent(api.DeploymentsResource, api.WorkloadEventUpdate, obj)
+	}
+	var received int
+	for version := 0; version != count; {
+		e := nextEvent(t, w, api.WorkloadEventUpdate)
+		next, _ := strconv.Atoi(e.Source.GetResourceVersion())
+		if next <= version {
+			t.Fatalf("unexpected event order, %d after %d", next, version)
+		}
+		version = next
+		received++
+	}
+	if received >= count {
+		t.Error("expecting events to be coalesced, received:", received)
+	}
+
+	w.Close()
+	select {
+	case _, ok := <-w.Events():
+		if ok {
+			t.Error("unexpected event after close")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("events not closed")
+	}
+}
@@ -0,0 +1,249 @@
+// Package spec loads RunParams from versioned YAML or JSON documents
+// so that workers can be described outside of the supervisor code.
+//
+//	apiVersion: horizon/v1alpha1
+//	kind: RunParamList
+//	items:
+//	- name: worker
+//	  image: ${WORKER_IMAGE:-worker:latest}
+//	  port: 8086
+package spec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	APIVersion = "horizon/v1alpha1"
+	KindList   = "RunParamList"
+)
+
+// List is the on-disk format of one or more RunParams
+type List struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       string         `json:"kind"`
+	Items      []api.RunParam `json:"items"`
+}
+
+// envRefRegexp matches ${VAR} and ${VAR:-default}
+// references along with the $${ escape sequence
+var envRefRegexp = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// LoadFile decodes the RunParams of the file at path, resolving
+// variable references against the process environment
+func LoadFile(path string) ([]api.RunParam, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	params, err := Decode(data, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return params, nil
+}
+
+// Decode returns the RunParams of data, a JSON List or a stream of
+// YAML List documents. References to variables, ${VAR} or
+// ${VAR:-default}, are replaced with values from lookup inside the
+// string values of the parsed documents, so a value cannot change the
+// structure of a document; the default is used when VAR is not set or
+// empty, as in the shell. $${ produces a literal ${. Expanded values
+// are strings, converted to the type of numeric and boolean fields when
+// they are JSON numbers or booleans. Unknown fields are rejected.
+func Decode(data []byte, lookup func(string) (string, bool)) ([]api.RunParam, error) {
+	var params []api.RunParam
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for i := 0; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		expanded, err := expandDocument(doc, lookup)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %s", i, err)
+		}
+		var list List
+		if err := yaml.UnmarshalStrict(expanded, &list); err != nil {
+			return nil, fmt.Errorf("document %d: %s", i, err)
+		}
+		if list.APIVersion != APIVersion {
+			return nil, fmt.Errorf("document %d: unsupported apiVersion %q, expecting %s", i, list.APIVersion, APIVersion)
+		}
+		if list.Kind != KindList {
+			return nil, fmt.Errorf("document %d: unsupported kind %q, expecting %s", i, list.Kind, KindList)
+		}
+		params = append(params, list.Items...)
+	}
+	return params, nil
+}
+
+// expandDocument parses doc and returns it as JSON
+// with the variable references of its values replaced
+func expandDocument(doc []byte, lookup func(string) (string, bool)) ([]byte, error) {
+	obj, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make(map[string]bool)
+	obj, err = expandValue(obj, reflect.TypeOf(List{}), lookup, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		var names []string
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined variables: %s", strings.Join(names, ", "))
+	}
+	return json.Marshal(obj)
+}
+
+// decodeJSON parses YAML or JSON data, keeping numbers as written
+func decodeJSON(data []byte) (interface{}, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	var obj interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// expandValue replaces the variable references of the string values
+// of obj, decoded from JSON, which is to be unmarshalled into typ
+func expandValue(obj interface{}, typ reflect.Type, lookup func(string) (string, bool), missing map[string]bool) (interface{}, error) {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch val := obj.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			expanded, err := expandValue(item, fieldType(typ, key), lookup, missing)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", key, err)
+			}
+			val[key] = expanded
+		}
+	case []interface{}:
+		var elemType reflect.Type
+		if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+			elemType = typ.Elem()
+		}
+		for i, item := range val {
+			expanded, err := expandValue(item, elemType, lookup, missing)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+			val[i] = expanded
+		}
+	case string:
+		expanded, ok := expandString(val, lookup, missing)
+		if !ok || typ == nil {
+			return expanded, nil
+		}
+		return convertString(expanded, typ)
+	}
+	return obj, nil
+}
+
+// fieldType returns the type of the value of key, the JSON
+// name of a field of the struct or the key of the map typ
+func fieldType(typ reflect.Type, key string) reflect.Type {
+	if typ == nil {
+		return nil
+	}
+	switch typ.Kind() {
+	case reflect.Map:
+		return typ.Elem()
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = field.Name
+			}
+			if name == key {
+				return field.Type
+			}
+		}
+	}
+	return nil
+}
+
+// jsonNumberRegexp matches the integers of the JSON syntax
+var jsonNumberRegexp = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+
+// convertString converts the expanded value s to the
+// integer or boolean typ, other types are kept as strings
+func convertString(s string, typ reflect.Type) (interface{}, error) {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !jsonNumberRegexp.MatchString(s) {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		return json.Number(s), nil
+	case reflect.Bool:
+		switch s {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid boolean %q, expecting true or false", s)
+	}
+	return s, nil
+}
+
+// expandString replaces the variable references of s using lookup and
+// returns true if s had any. Variables that are not set and have no
+// default are added to missing.
+func expandString(s string, lookup func(string) (string, bool), missing map[string]bool) (string, bool) {
+	var expanded bool
+	result := envRefRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		expanded = true
+		if ref == "$${" {
+			return "${"
+		}
+		match := envRefRegexp.FindStringSubmatch(ref)
+		val, ok := lookup(match[1])
+		if strings.Contains(ref, ":-") && val == "" {
+			return match[2]
+		}
+		if !ok {
+			missing[match[1]] = true
+			return ref
+		}
+		return val
+	})
+	return result, expanded
+}
@@ -0,0 +1,212 @@
+package spec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vladimirvivien/horizon/pkg/api"
+)
+
+func TestDecode(t *testing.T) {
+	env := map[string]string{
+		"WORKER_IMAGE": "registry/worker:1.2",
+		"EMPTY":        "",
+		"INJECT":       "a: b #c",
+		"REPLICAS":     "2",
+		"OFF":          "off",
+		"YES":          "yes",
+		"OCTAL":        "0123",
+		"ENABLED":      "true",
+	}
+	lookup := func(name string) (string, bool) {
+		val, ok := env[name]
+		return val, ok
+	}
+
+	tests := []struct {
+		name       string
+		data       string
+		test       func(*testing.T, []api.RunParam)
+		shouldFail bool
+	}{
+		{
+			name: "yaml stream",
+			data: `
+apiVersion: horizon/v1alpha1
+kind: RunParamList
+items:
+- name: worker
+  namespace: workers
+  image: ${WORKER_IMAGE}
+  port: 8086
+  replicas: 3
+  service: ClusterIP
+  envs: ["LOG_LEVEL=${LOG_LEVEL:-info}", "POD_IP=$(POD_IP)"]
+  requests:
+    cpu: 250m
+  readinessProbe:
+    http:
+      path: /healthz
+---
+apiVersion: horizon/v1alpha1
+kind: RunParamList
+items:
+- kind: CronJob
+  name: compaction
+  image: compactor:latest
+  schedule: "0 2 * * *"
+  args: ["--prefix", "$${HOME}"]
+`,
+			test: func(t *testing.T, params []api.RunParam) {
+				if len(params) != 2 {
+					t.Fatal("unexpected number of params:", len(params))
+				}
+				worker := params[0]
+				if worker.Image != "registry/worker:1.2" || worker.Replicas != 3 || worker.Service != api.ServiceClusterIP {
+					t.Errorf("unexpected worker: %#v", worker)
+				}
+				if worker.Envs[0] != "LOG_LEVEL=info" || worker.Envs[1] != "POD_IP=$(POD_IP)" {
+					t.Error("unexpected envs:", worker.Envs)
+				}
+				if worker.Requests.CPU != "250m" || worker.ReadinessProbe == nil || worker.ReadinessProbe.HTTP.Path != "/healthz" {
+					t.Errorf("unexpected nested fields: %#v", worker)
+				}
+				cron := params[1]
+				if cron.Kind != api.WorkloadCronJob || cron.Schedule != "0 2 * * *" || cron.Args[1] != "${HOME}" {
+					t.Errorf("unexpected cronjob: %#v", cron)
+				}
+			},
+		},
+		{
+			name: "json",
+			data: `{"apiVersion": "horizon/v1alpha1", "kind": "RunParamList", "items": [{"name": "worker", "image": "worker${EMPTY}"}]}`,
+			test: func(t *testing.T, params []api.RunParam) {
+				if len(params) != 1 || params[0].Image != "worker" {
+					t.Errorf("unexpected params: %#v", params)
+				}
+			},
+		},
+		{
+			name: "values kept as strings",
+			data: "apiVersion: horizon/v1alpha1\nkind: RunParamList\nitems:\n- name: worker\n  image: ${INJECT}\n  command:\n  - ${INJECT}\n  args: [\"${EMPTY:-default}\", \"$${INJECT}\"]\n  replicas: ${REPLICAS}\n",
+			test: func(t *testing.T, params []api.RunParam) {
+				if len(params) != 1 {
+					t.Fatal("unexpected number of params:", len(params))
+				}
+				worker := params[0]
+				if worker.Image != "a: b #c" || len(worker.Command) != 1 || worker.Command[0] != "a: b #c" {
+					t.Errorf("unexpected expansion: %#v", worker)
+				}
+				if worker.Args[0] != "default" || worker.Args[1] != "${INJECT}" {
+					t.Error("unexpected args:", worker.Args)
+				}
+				if worker.Replicas != 2 {
+					t.Error("unexpected replicas:", worker.Replicas)
+				}
+			},
+		},
+		{
+			name: "values not retyped",
+			data: "apiVersion: horizon/v1alpha1\nkind: RunParamList\nitems:\n- name: worker\n  image: worker:latest\n  workingDir: ${OCTAL}\n  args: [\"${OFF}\", \"${YES}\", \"${OCTAL}\"]\n  envs:\n  - ${OFF}\n  - MODE=${YES}\n  serviceAccount: ${ENABLED}\n",
+			test: func(t *testing.T, params []api.RunParam) {
+				worker := params[0]
+				if worker.WorkingDir != "0123" {
+					t.Error("unexpected working dir:", worker.WorkingDir)
+				}
+				if len(worker.Args) != 3 || worker.Args[0] != "off" || worker.Args[1] != "yes" || worker.Args[2] != "0123" {
+					t.Error("unexpected args:", worker.Args)
+				}
+				if len(worker.Envs) != 2 || worker.Envs[0] != "off" || worker.Envs[1] != "MODE=yes" {
+					t.Error("unexpected envs:", worker.Envs)
+				}
+				if !worker.ServiceAccount {
+					t.Error("expecting service account")
+				}
+			},
+		},
+		{
+			name:       "invalid integer",
+			data:       "apiVersion: horizon/v1alpha1\nkind: RunParamList\nitems:\n- name: worker\n  image: worker:latest\n  replicas: ${OCTAL}\n",
+			shouldFail: true,
+		},
+		{
+			name:       "invalid boolean",
+			data:       "apiVersion: horizon/v1alpha1\nkind: RunParamList\nitems:\n- name: worker\n  image: worker:latest\n  serviceAccount: ${YES}\n",
+			shouldFail: true,
+		},
+		{
+			name:       "unknown field",
+			data:       "apiVersion: horizon/v1alpha1\nkind: RunParamList\nitems:\n- name: worker\n  imag: worker:latest\n",
+			shouldFail: true,
+		},
+		{
+			name:       "unsupported version",
+			data:       "apiVersion: horizon/v2\nkind: RunParamList\nitems: []\n",
+			shouldFail: true,
+		},
+		{
+			name:       "unsupported kind",
+			data:       "apiVersion: horizon/v1alpha1\nkind: Deployment\n",
+			shouldFail: true,
+		},
+		{
+			name:       "undefined variable",
+			data:       "apiVersion: horizon/v1alpha1\nkind: RunParamList\nitems:\n- name: worker\n  image: ${UNDEFINED}\n",
+			shouldFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, err := Decode([]byte(test.data), lookup)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expecting failure, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.test(t, params)
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("SPEC_TEST_REPLICAS", "2")
+	defer os.Unsetenv("SPEC_TEST_REPLICAS")
+
+	path := filepath.Join(dir, "workers.yaml")
+	data := "apiVersion: horizon/v1alpha1\nkind: RunParamList\nitems:\n- name: worker\n  image: worker:latest\n  replicas: ${SPEC_TEST_REPLICAS}\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	params, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 1 || params[0].Replicas != 2 {
+		t.Errorf("unexpected params: %#v", params)
+	}
+
+	if _, err := LoadFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expecting failure for missing file")
+	}
+	if err := ioutil.WriteFile(path, []byte("apiVersion: horizon/v1alpha1\nkind: RunParamList\nitems:\n- name: ${NOT_SET_FOR_SPEC_TEST}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), "NOT_SET_FOR_SPEC_TEST") {
+		t.Error("expecting undefined variable error, got:", err)
+	}
+}